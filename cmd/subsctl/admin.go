@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gotest_23.07.25/internal/health"
+	"gotest_23.07.25/internal/http-server/handlers"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// errNotReady означает, что сервис ответил на /readyz отказом; результат проверок уже выведен.
+var errNotReady = errors.New("service is not ready")
+
+func runKeys(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: expected keys issue, keys list or keys revoke", errUsage)
+	}
+
+	switch sub, args := args[0], args[1:]; sub {
+	case "issue":
+		return runIssueKey(ctx, a, args)
+	case "list":
+		return runListKeys(ctx, a, args)
+	case "revoke":
+		return runRevokeKey(ctx, a, args)
+	default:
+		return fmt.Errorf("%w: unknown keys command %q", errUsage, sub)
+	}
+}
+
+func runIssueKey(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("keys issue", "keys issue -name NAME -scopes SCOPES")
+	name := fs.String("name", "", "key name, e.g. the client service")
+	scopes := fs.String("scopes", "", "comma-separated scopes: subscriptions:read, subscriptions:write, reports:read, admin")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	if err := required(map[string]bool{"name": *name != "", "scopes": *scopes != ""}); err != nil {
+		return err
+	}
+
+	rb := handlers.IssueKeyRequest{Name: *name}
+	for _, scope := range strings.Split(*scopes, ",") {
+		rb.Scopes = append(rb.Scopes, strings.TrimSpace(scope))
+	}
+
+	resp, err := a.client.IssueKey(ctx, rb)
+	if err != nil {
+		return err
+	}
+
+	res := keysResult(resp, resp.APIKey)
+	res.header = append(res.header, "key")
+	res.rows[0] = append(res.rows[0], resp.Key)
+	if err := a.print(res); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "store the key now: it cannot be shown again")
+	return nil
+}
+
+func runListKeys(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("keys list", "keys list")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := noArgs(args); err != nil {
+		return err
+	}
+
+	keys, err := a.client.ListKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.print(keysResult(keys, keys...))
+}
+
+func runRevokeKey(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("keys revoke", "keys revoke ID")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("%w: expected key ID", errUsage)
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id < 1 {
+		return fmt.Errorf("%w: key id must be a positive number, got %q", errUsage, args[0])
+	}
+
+	if err := a.client.RevokeKey(ctx, id); err != nil {
+		return err
+	}
+
+	return a.print(result{
+		value:  handlers.DeleteResponse{Status: "success", Message: "API key revoked"},
+		header: []string{"id", "status"},
+		rows:   [][]string{{args[0], "revoked"}},
+	})
+}
+
+func keysResult(value any, keys ...postgre.APIKey) result {
+	res := result{value: value, header: []string{"id", "name", "prefix", "scopes", "created_at", "revoked_at"}}
+	for _, k := range keys {
+		res.rows = append(res.rows, []string{
+			strconv.FormatInt(k.ID, 10),
+			k.Name,
+			k.Prefix,
+			strings.Join(k.Scopes, ","),
+			formatDate(&k.CreatedAt),
+			formatDate(k.RevokedAt),
+		})
+	}
+	return res
+}
+
+func runHealth(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("health", "health")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := noArgs(args); err != nil {
+		return err
+	}
+
+	if err := a.client.Health(ctx); err != nil {
+		return err
+	}
+
+	return a.print(result{
+		value:  handlers.LivenessResponse{Status: health.StatusOK},
+		header: []string{"status"},
+		rows:   [][]string{{health.StatusOK}},
+	})
+}
+
+func runReady(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("ready", "ready")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := noArgs(args); err != nil {
+		return err
+	}
+
+	report, err := a.client.Ready(ctx)
+	if err != nil {
+		return err
+	}
+
+	res := result{value: report, header: []string{"check", "status", "error"}}
+	res.rows = append(res.rows, []string{"service", report.Status, ""})
+	for _, name := range slices.Sorted(maps.Keys(report.Checks)) {
+		check := report.Checks[name]
+		res.rows = append(res.rows, []string{name, check.Status, check.Error})
+	}
+	if err := a.print(res); err != nil {
+		return err
+	}
+
+	if !report.Ready() {
+		return errNotReady
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"gotest_23.07.25/internal/client"
+)
+
+// config - настройки subsctl. Значения из файла переопределяются переменными окружения, а те - флагами.
+type config struct {
+	URL    string `yaml:"url" env:"SUBSCTL_URL" env-default:"http://localhost:8080"`
+	APIKey string `yaml:"api_key" env:"SUBSCTL_API_KEY"`
+	// Token - JWT для заголовка Authorization: Bearer.
+	Token   string        `yaml:"token" env:"SUBSCTL_TOKEN"`
+	Timeout time.Duration `yaml:"timeout" env:"SUBSCTL_TIMEOUT" env-default:"30s"`
+	Output  string        `yaml:"output" env:"SUBSCTL_OUTPUT" env-default:"table"`
+}
+
+// globalFlags - флаги, общие для всех команд.
+type globalFlags struct {
+	config  string
+	url     string
+	apiKey  string
+	token   string
+	timeout time.Duration
+	output  string
+}
+
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.config, "config", "", "config file (default $SUBSCTL_CONFIG or ~/.config/subsctl/config.yaml)")
+	fs.StringVar(&g.url, "url", "", "service base url ($SUBSCTL_URL)")
+	fs.StringVar(&g.apiKey, "api-key", "", "API key ($SUBSCTL_API_KEY)")
+	fs.StringVar(&g.token, "token", "", "JWT bearer token ($SUBSCTL_TOKEN)")
+	fs.DurationVar(&g.timeout, "timeout", 0, "request timeout ($SUBSCTL_TIMEOUT)")
+	fs.StringVar(&g.output, "output", "", "output format: table, json or csv ($SUBSCTL_OUTPUT)")
+	fs.StringVar(&g.output, "o", "", "shorthand for -output")
+}
+
+// loadConfig читает файл настроек, переменные окружения и применяет флаги.
+// Файл по умолчанию необязателен, явно указанный - обязателен.
+func loadConfig(g *globalFlags) (*config, error) {
+	path, explicit := g.config, g.config != ""
+	if !explicit {
+		path, explicit = os.Getenv("SUBSCTL_CONFIG"), os.Getenv("SUBSCTL_CONFIG") != ""
+	}
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "subsctl", "config.yaml")
+		}
+	}
+
+	useFile := explicit
+	if !useFile && path != "" {
+		_, err := os.Stat(path)
+		useFile = !errors.Is(err, os.ErrNotExist)
+	}
+
+	var cfg config
+	if useFile {
+		if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+		}
+	} else if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to read environment: %w", err)
+	}
+
+	if g.url != "" {
+		cfg.URL = g.url
+	}
+	if g.apiKey != "" {
+		cfg.APIKey = g.apiKey
+	}
+	if g.token != "" {
+		cfg.Token = g.token
+	}
+	if g.timeout != 0 {
+		cfg.Timeout = g.timeout
+	}
+	if g.output != "" {
+		cfg.Output = g.output
+	}
+
+	switch cfg.Output {
+	case outputTable, outputJSON, outputCSV:
+	default:
+		return nil, fmt.Errorf("%w: unknown output format %q, expected one of: %s, %s, %s", errUsage, cfg.Output, outputTable, outputJSON, outputCSV)
+	}
+
+	return &cfg, nil
+}
+
+// newClient возвращает клиента API по настройкам.
+func newClient(cfg *config) (*client.Client, error) {
+	return client.New(client.Options{
+		BaseURL: cfg.URL,
+		APIKey:  cfg.APIKey,
+		Token:   cfg.Token,
+		Timeout: cfg.Timeout,
+	})
+}
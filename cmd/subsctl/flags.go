@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest_23.07.25/internal/client"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// priceFlag - необязательная цена; nil, если флаг не указан.
+type priceFlag struct {
+	v *uint16
+}
+
+func (f *priceFlag) String() string {
+	if f.v == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*f.v), 10)
+}
+
+func (f *priceFlag) Set(s string) error {
+	price, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return fmt.Errorf("price must be a whole number from 0 to 65535")
+	}
+	p := uint16(price)
+	f.v = &p
+	return nil
+}
+
+// dateFlag - необязательная дата YYYY-MM-DD или RFC 3339; nil, если флаг не указан.
+type dateFlag struct {
+	v *time.Time
+}
+
+func (f *dateFlag) String() string {
+	return formatDate(f.v)
+}
+
+func (f *dateFlag) Set(s string) error {
+	t, err := parseDate(s)
+	if err != nil {
+		return fmt.Errorf("date must be YYYY-MM-DD or RFC 3339")
+	}
+	f.v = &t
+	return nil
+}
+
+// filterFlags - флаги фильтров списка и выгрузки.
+type filterFlags struct {
+	service  string
+	user     string
+	activeOn string
+	priceMin priceFlag
+	priceMax priceFlag
+	sort     string
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.service, "service", "", "filter by service name")
+	fs.StringVar(&f.user, "user", "", "filter by user UUID")
+	fs.StringVar(&f.activeOn, "active-on", "", "only subscriptions active on date YYYY-MM-DD")
+	fs.Var(&f.priceMin, "price-min", "minimal price")
+	fs.Var(&f.priceMax, "price-max", "maximal price")
+	fs.StringVar(&f.sort, "sort", "", "sort by price, start_date or service_name; '-' prefix for descending")
+}
+
+func (f *filterFlags) filter() client.Filter {
+	return client.Filter{
+		ServiceName: f.service,
+		UserID:      f.user,
+		ActiveOn:    f.activeOn,
+		PriceMin:    f.priceMin.v,
+		PriceMax:    f.priceMax.v,
+		Sort:        f.sort,
+	}
+}
+
+// keyArg возвращает ключ подписки из аргументов: ID или SERVICE USER_ID.
+func keyArg(args []string) (postgre.SubscriptionKey, error) {
+	switch len(args) {
+	case 1:
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || id < 1 {
+			return postgre.SubscriptionKey{}, fmt.Errorf("%w: subscription id must be a positive number, got %q", errUsage, args[0])
+		}
+		return postgre.SubscriptionKey{ID: id}, nil
+	case 2:
+		return postgre.SubscriptionKey{ServiceName: args[0], UserID: args[1]}, nil
+	default:
+		return postgre.SubscriptionKey{}, fmt.Errorf("%w: expected ID or SERVICE USER_ID", errUsage)
+	}
+}
+
+// noArgs проверяет, что у команды нет позиционных аргументов.
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: unexpected arguments: %s", errUsage, strings.Join(args, " "))
+	}
+	return nil
+}
+
+// required проверяет, что обязательные флаги заданы; set - признак заданного флага по его имени.
+func required(set map[string]bool) error {
+	var missing []string
+	for name, ok := range set {
+		if !ok {
+			missing = append(missing, "-"+name)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("%w: missing required flags: %s", errUsage, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest_23.07.25/internal/postgre"
+)
+
+// readRows читает записи о подписках для массовых операций. Формат определяется по расширению файла:
+// .json - массив объектов, .ndjson и .jsonl - объект на строке, .csv - таблица с заголовком
+// из колонок выгрузки (id, service_name, price, user_id, start_date, end_date, version).
+// Поэтому выгрузку export можно отредактировать и передать обратно в update или delete.
+func readRows(path string) ([]postgre.RequestFields, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []postgre.RequestFields
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.NewDecoder(f).Decode(&rows)
+	case ".ndjson", ".jsonl":
+		rows, err = readNDJSON(f)
+	case ".csv":
+		rows, err = readCSV(f)
+	default:
+		return nil, fmt.Errorf("unsupported file extension %q, expected .json, .ndjson, .jsonl or .csv", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: no records", path)
+	}
+
+	return rows, nil
+}
+
+func readNDJSON(r io.Reader) ([]postgre.RequestFields, error) {
+	var rows []postgre.RequestFields
+
+	dec := json.NewDecoder(r)
+	for {
+		var rb postgre.RequestFields
+		err := dec.Decode(&rb)
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(rows)+1, err)
+		}
+		rows = append(rows, rb)
+	}
+}
+
+func readCSV(r io.Reader) ([]postgre.RequestFields, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		switch name {
+		case "id", "service_name", "price", "user_id", "start_date", "end_date", "version":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("unknown csv column: %s", name)
+		}
+	}
+
+	var rows []postgre.RequestFields
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rb, err := csvRow(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rows = append(rows, rb)
+	}
+}
+
+// csvRow разбирает строку CSV. Отсутствующие и пустые колонки оставляют поле нулевым.
+func csvRow(record []string, columns map[string]int) (postgre.RequestFields, error) {
+	var rb postgre.RequestFields
+
+	get := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var err error
+	if v := get("id"); v != "" {
+		if rb.ID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return rb, fmt.Errorf("invalid id %q", v)
+		}
+	}
+	if v := get("version"); v != "" {
+		if rb.Version, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return rb, fmt.Errorf("invalid version %q", v)
+		}
+	}
+	if v := get("price"); v != "" {
+		price, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return rb, fmt.Errorf("invalid price %q", v)
+		}
+		rb.Price = uint16(price)
+	}
+	if v := get("start_date"); v != "" {
+		if rb.StartDate, err = parseDate(v); err != nil {
+			return rb, fmt.Errorf("invalid start_date %q", v)
+		}
+	}
+	if v := get("end_date"); v != "" {
+		end, err := parseDate(v)
+		if err != nil {
+			return rb, fmt.Errorf("invalid end_date %q", v)
+		}
+		rb.EndDate = &end
+	}
+	rb.ServiceName = get("service_name")
+	rb.UserId = get("user_id")
+
+	return rb, nil
+}
+
+// parseDate разбирает дату в формате YYYY-MM-DD или RFC 3339, как сервис в фильтрах и CSV.
+func parseDate(v string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// rowKey возвращает ключ подписки записи из файла: по id, если он задан, иначе по имени сервиса и пользователю.
+func rowKey(rb postgre.RequestFields) (postgre.SubscriptionKey, error) {
+	if rb.ID != 0 {
+		return postgre.SubscriptionKey{ID: rb.ID}, nil
+	}
+	if rb.ServiceName == "" || rb.UserId == "" {
+		return postgre.SubscriptionKey{}, errors.New("record must have id or both service_name and user_id")
+	}
+	return postgre.SubscriptionKey{ServiceName: rb.ServiceName, UserID: rb.UserId}, nil
+}
+
+// keyString возвращает ключ подписки для вывода.
+func keyString(key postgre.SubscriptionKey) string {
+	if key.ID != 0 {
+		return strconv.FormatInt(key.ID, 10)
+	}
+	return key.ServiceName + "/" + key.UserID
+}
@@ -0,0 +1,177 @@
+// subsctl - клиент командной строки для API подписок.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gotest_23.07.25/internal/client"
+)
+
+const usage = `usage: subsctl <command> [flags] [args]
+
+subscriptions:
+  create       create a subscription, or many with -from-file
+  get          show a subscription: get ID | get SERVICE USER_ID
+  update       replace price and dates: update ID | update SERVICE USER_ID, or many with -from-file
+  patch        change some fields: patch ID | patch SERVICE USER_ID
+  delete       delete a subscription: delete ID | delete SERVICE USER_ID, or many with -from-file
+  list         list subscriptions page by page, or all with -all
+  export       stream subscriptions as csv or ndjson
+
+reports:
+  range-price  total spend for a period
+  report       spend for a period grouped by service, user or month
+
+admin:
+  keys issue   issue an API key
+  keys list    list API keys
+  keys revoke  revoke an API key: keys revoke ID
+  health       check that the service is alive
+  ready        check that the service is ready to serve requests
+
+Global flags (any command):
+  -url URL          service base url ($SUBSCTL_URL, default http://localhost:8080)
+  -api-key KEY      API key ($SUBSCTL_API_KEY)
+  -token JWT        bearer token ($SUBSCTL_TOKEN)
+  -timeout D        per-request timeout; export only waits this long for the
+                    response to start ($SUBSCTL_TIMEOUT, default 30s)
+  -o, -output F     table, json or csv ($SUBSCTL_OUTPUT, default table)
+  -config FILE      yaml with url, api_key, token, timeout, output
+                    ($SUBSCTL_CONFIG, default ~/.config/subsctl/config.yaml)
+
+Run "subsctl <command> -h" for command flags.
+`
+
+// errUsage означает неверные аргументы команды.
+var errUsage = errors.New("invalid arguments")
+
+// errPartial означает, что часть записей массовой операции не обработана; результат по записям уже выведен.
+var errPartial = errors.New("some records failed, see the output")
+
+// commands:
+var commands = map[string]func(ctx context.Context, a *app, args []string) error{
+	"create":      runCreate,
+	"get":         runGet,
+	"update":      runUpdate,
+	"patch":       runPatch,
+	"delete":      runDelete,
+	"list":        runList,
+	"export":      runExport,
+	"range-price": runRangePrice,
+	"report":      runReport,
+	"keys":        runKeys,
+	"health":      runHealth,
+	"ready":       runReady,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout))
+}
+
+// run выполняет команду и возвращает код выхода: 2 для неверных аргументов, 1 для остальных ошибок.
+func run(args []string, stdout io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := cmd(ctx, &app{stdout: stdout}, args[1:])
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "subsctl %s: %s\n", args[0], err)
+		return 2
+	default:
+		fmt.Fprintf(os.Stderr, "subsctl %s: %s\n", args[0], errorMessage(err))
+		return 1
+	}
+}
+
+// errorMessage возвращает текст ошибки для пользователя: для ошибок API - без префиксов операций клиента.
+func errorMessage(err error) string {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Error()
+	}
+	return err.Error()
+}
+
+// app - окружение команды: вывод, общие флаги, настройки и клиент API после разбора флагов.
+type app struct {
+	stdout io.Writer
+	global globalFlags
+	cfg    *config
+	client *client.Client
+}
+
+// flagSet возвращает набор флагов команды name с общими флагами.
+func (a *app) flagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: subsctl %s\n\nflags:\n", synopsis)
+		fs.PrintDefaults()
+	}
+	a.global.register(fs)
+	return fs
+}
+
+// parse разбирает флаги, в том числе после позиционных аргументов, загружает настройки
+// и создает клиента API. Возвращает позиционные аргументы.
+func (a *app) parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %w", errUsage, err)
+		}
+
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		// После "--" все аргументы позиционные.
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	cfg, err := loadConfig(&a.global)
+	if err != nil {
+		return nil, err
+	}
+	a.cfg = cfg
+
+	a.client, err = newClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	return positional, nil
+}
+
+// print выводит результат в формате из настроек.
+func (a *app) print(res result) error {
+	return res.write(a.stdout, a.cfg.Output)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gotest_23.07.25/internal/postgre"
+)
+
+// output formats:
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// subscriptionColumns - колонки подписки, как в CSV-выгрузке сервиса.
+var subscriptionColumns = []string{"id", "service_name", "price", "user_id", "start_date", "end_date", "version"}
+
+// result - результат команды: value выводится в JSON, header и rows - в таблицу и CSV.
+// footer - итоговая строка, только для таблицы.
+type result struct {
+	value  any
+	header []string
+	rows   [][]string
+	footer []string
+}
+
+// write выводит результат в формате format.
+func (res result) write(w io.Writer, format string) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res.value)
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(res.header)
+		cw.WriteAll(res.rows)
+		return cw.Error()
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(res.header, "\t")))
+		for _, row := range res.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		if res.footer != nil {
+			fmt.Fprintln(tw, strings.Join(res.footer, "\t"))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q, expected one of: %s, %s, %s", format, outputTable, outputJSON, outputCSV)
+	}
+}
+
+// subscriptionsResult возвращает результат со списком подписок.
+func subscriptionsResult(value any, subs ...postgre.RequestFields) result {
+	res := result{value: value, header: subscriptionColumns}
+	for _, rb := range subs {
+		res.rows = append(res.rows, subscriptionRow(rb))
+	}
+	return res
+}
+
+func subscriptionRow(rb postgre.RequestFields) []string {
+	return []string{
+		formatID(rb.ID),
+		rb.ServiceName,
+		strconv.FormatUint(uint64(rb.Price), 10),
+		rb.UserId,
+		formatDate(&rb.StartDate),
+		formatDate(rb.EndDate),
+		formatID(rb.Version),
+	}
+}
+
+// formatID возвращает пустую строку для нулевых id и версий, которых нет в ответе.
+func formatID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gotest_23.07.25/internal/http-server/handlers"
+)
+
+// report group fields:
+const (
+	groupByServiceName = "service_name"
+	groupByUserID      = "user_id"
+	groupByMonth       = "month"
+)
+
+// periodFlags - флаги периода и фильтров расчета расходов.
+type periodFlags struct {
+	start   dateFlag
+	end     dateFlag
+	service string
+	user    string
+}
+
+func (p *periodFlags) register(fs *flag.FlagSet) {
+	fs.Var(&p.start, "start", "period start YYYY-MM-DD")
+	fs.Var(&p.end, "end", "period end YYYY-MM-DD")
+	fs.StringVar(&p.service, "service", "", "only this service")
+	fs.StringVar(&p.user, "user", "", "only this user UUID")
+}
+
+func (p *periodFlags) body() (handlers.RangeRequestBody, error) {
+	if err := required(map[string]bool{"start": p.start.v != nil, "end": p.end.v != nil}); err != nil {
+		return handlers.RangeRequestBody{}, err
+	}
+	return handlers.RangeRequestBody{
+		StartDate:   *p.start.v,
+		EndDate:     *p.end.v,
+		ServiceName: p.service,
+		UserID:      p.user,
+	}, nil
+}
+
+func runRangePrice(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("range-price", "range-price -start DATE -end DATE [-service S] [-user USER_ID]")
+	var period periodFlags
+	period.register(fs)
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	body, err := period.body()
+	if err != nil {
+		return err
+	}
+
+	price, err := a.client.RangePrice(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	return a.print(result{
+		value:  handlers.RangeResponse{Status: "success", Price: price},
+		header: []string{"start_date", "end_date", "service_name", "user_id", "price"},
+		rows: [][]string{{
+			formatDate(&body.StartDate),
+			formatDate(&body.EndDate),
+			body.ServiceName,
+			body.UserID,
+			strconv.FormatUint(price, 10),
+		}},
+	})
+}
+
+func runReport(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("report", "report -start DATE -end DATE [-service S] [-user USER_ID] [-group-by FIELDS]")
+	var period periodFlags
+	period.register(fs)
+	groupBy := fs.String("group-by", "", "comma-separated fields: service_name, user_id, month")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := noArgs(args); err != nil {
+		return err
+	}
+	body, err := period.body()
+	if err != nil {
+		return err
+	}
+
+	rb := handlers.ReportRequestBody{RangeRequestBody: body}
+	if *groupBy != "" {
+		for _, field := range strings.Split(*groupBy, ",") {
+			rb.GroupBy = append(rb.GroupBy, strings.TrimSpace(field))
+		}
+	}
+	if err := checkGroupBy(rb.GroupBy); err != nil {
+		return err
+	}
+
+	resp, err := a.client.Report(ctx, rb)
+	if err != nil {
+		return err
+	}
+
+	return a.print(reportResult(resp, rb.GroupBy))
+}
+
+// reportResult возвращает группы отчета с колонками полей группировки; в таблице последняя строка - итог.
+func reportResult(resp *handlers.ReportResponse, groupBy []string) result {
+	res := result{value: resp}
+	res.header = append(res.header, groupBy...)
+	res.header = append(res.header, "count", "price")
+
+	for _, g := range resp.Groups {
+		var row []string
+		for _, field := range groupBy {
+			switch field {
+			case groupByServiceName:
+				row = append(row, g.ServiceName)
+			case groupByUserID:
+				row = append(row, g.UserId)
+			case groupByMonth:
+				row = append(row, g.Month)
+			default:
+				row = append(row, "")
+			}
+		}
+		row = append(row, strconv.FormatUint(g.Count, 10), strconv.FormatUint(g.Price, 10))
+		res.rows = append(res.rows, row)
+	}
+
+	res.footer = make([]string, len(res.header))
+	res.footer[0] = "TOTAL"
+	res.footer[len(res.footer)-1] = strconv.FormatUint(resp.Price, 10)
+
+	return res
+}
+
+// checkGroupBy возвращает ошибку для неизвестных полей группировки до запроса к сервису.
+func checkGroupBy(fields []string) error {
+	for _, f := range fields {
+		switch f {
+		case groupByServiceName, groupByUserID, groupByMonth:
+		default:
+			return fmt.Errorf("%w: unknown group-by field %q", errUsage, f)
+		}
+	}
+	return nil
+}
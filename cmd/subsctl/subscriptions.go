@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gotest_23.07.25/internal/client"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// bulkResult - результат обработки одной записи файла в update и delete с -from-file.
+type bulkResult struct {
+	Row     int    `json:"row"`
+	Key     string `json:"key"`
+	Status  string `json:"status"`
+	Version int64  `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// bulk statuses:
+const (
+	statusUpdated = "updated"
+	statusDeleted = "deleted"
+	statusFailed  = "failed"
+)
+
+func runCreate(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("create", "create -service S -user USER_ID -price P -start DATE [-end DATE]\n       subsctl create -from-file FILE [-mode atomic|per_row]")
+	service := fs.String("service", "", "service name")
+	user := fs.String("user", "", "user UUID")
+	var price priceFlag
+	fs.Var(&price, "price", "monthly price")
+	var start, end dateFlag
+	fs.Var(&start, "start", "start date YYYY-MM-DD")
+	fs.Var(&end, "end", "end date YYYY-MM-DD")
+	fromFile := fs.String("from-file", "", "create all records from a .json, .ndjson, .jsonl or .csv file in one batch request")
+	mode := fs.String("mode", client.BatchAtomic, "batch mode for -from-file: atomic (all or nothing) or per_row")
+	idempotencyKey := fs.String("idempotency-key", "", "Idempotency-Key header: a retry with the same key returns the saved response")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := noArgs(args); err != nil {
+		return err
+	}
+
+	if *fromFile != "" {
+		return createBatch(ctx, a, *fromFile, *mode, *idempotencyKey)
+	}
+
+	if err := required(map[string]bool{
+		"service": *service != "",
+		"user":    *user != "",
+		"price":   price.v != nil,
+		"start":   start.v != nil,
+	}); err != nil {
+		return err
+	}
+
+	rb := postgre.RequestFields{
+		ServiceName: *service,
+		UserId:      *user,
+		Price:       *price.v,
+		StartDate:   *start.v,
+		EndDate:     end.v,
+	}
+	created, err := a.client.Create(ctx, rb, *idempotencyKey)
+	if err != nil {
+		return err
+	}
+
+	return a.print(subscriptionsResult(created, *created))
+}
+
+// createBatch создает записи из файла одним пакетным запросом. id и version записей файла не передаются.
+func createBatch(ctx context.Context, a *app, path, mode, idempotencyKey string) error {
+	rows, err := readRows(path)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		rows[i].ID, rows[i].Version = 0, 0
+	}
+
+	resp, err := a.client.CreateBatch(ctx, rows, mode, idempotencyKey)
+	if err != nil {
+		return err
+	}
+
+	res := result{value: resp, header: []string{"row", "status", "id", "error"}}
+	for _, r := range resp.Results {
+		msg := r.Error
+		for _, fe := range r.Errors {
+			msg = strings.TrimPrefix(msg+"; "+fe.Message, "; ")
+		}
+		res.rows = append(res.rows, []string{strconv.Itoa(r.Row), r.Status, formatID(r.ID), msg})
+	}
+	if err := a.print(res); err != nil {
+		return err
+	}
+
+	if resp.Created < len(rows) {
+		return fmt.Errorf("%w: created %d of %d (%s)", errPartial, resp.Created, len(rows), resp.Mode)
+	}
+	return nil
+}
+
+func runGet(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("get", "get ID | get SERVICE USER_ID")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	key, err := keyArg(args)
+	if err != nil {
+		return err
+	}
+
+	rb, err := a.client.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return a.print(subscriptionsResult(rb, *rb))
+}
+
+func runUpdate(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("update", "update ID|SERVICE USER_ID -price P -start DATE [-end DATE] [-if-match VERSION]\n       subsctl update -from-file FILE")
+	var price priceFlag
+	fs.Var(&price, "price", "monthly price")
+	var start, end dateFlag
+	fs.Var(&start, "start", "start date YYYY-MM-DD")
+	fs.Var(&end, "end", "end date YYYY-MM-DD; without it the subscription has no end date")
+	version := fs.Int64("if-match", 0, "update only if the record still has this version")
+	fromFile := fs.String("from-file", "", "update every record of a file, e.g. an edited export; records are matched by id or service_name and user_id, version is checked if set")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *fromFile != "" {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		return bulk(ctx, a, *fromFile, statusUpdated, func(key postgre.SubscriptionKey, rb postgre.RequestFields) (int64, error) {
+			return a.client.Update(ctx, key, postgre.RequestUpdateFields{Price: rb.Price, StartDate: rb.StartDate, EndDate: rb.EndDate}, rb.Version)
+		})
+	}
+
+	key, err := keyArg(args)
+	if err != nil {
+		return err
+	}
+	if err := required(map[string]bool{"price": price.v != nil, "start": start.v != nil}); err != nil {
+		return err
+	}
+
+	newVersion, err := a.client.Update(ctx, key, postgre.RequestUpdateFields{Price: *price.v, StartDate: *start.v, EndDate: end.v}, *version)
+	if err != nil {
+		return err
+	}
+
+	rb, err := a.client.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("updated to version %d, but failed to read it back: %w", newVersion, err)
+	}
+
+	return a.print(subscriptionsResult(rb, *rb))
+}
+
+func runPatch(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("patch", "patch ID|SERVICE USER_ID [-price P] [-start DATE] [-end DATE | -clear-end] [-if-match VERSION]")
+	var price priceFlag
+	fs.Var(&price, "price", "new monthly price")
+	var start, end dateFlag
+	fs.Var(&start, "start", "new start date YYYY-MM-DD")
+	fs.Var(&end, "end", "new end date YYYY-MM-DD")
+	clearEnd := fs.Bool("clear-end", false, "remove the end date")
+	version := fs.Int64("if-match", 0, "change only if the record still has this version")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	key, err := keyArg(args)
+	if err != nil {
+		return err
+	}
+
+	patch := client.Patch{Price: price.v, StartDate: start.v, EndDate: end.v, ClearEndDate: *clearEnd}
+	switch {
+	case patch.EndDate != nil && patch.ClearEndDate:
+		return fmt.Errorf("%w: -end and -clear-end are mutually exclusive", errUsage)
+	case patch.Price == nil && patch.StartDate == nil && patch.EndDate == nil && !patch.ClearEndDate:
+		return fmt.Errorf("%w: nothing to change, set -price, -start, -end or -clear-end", errUsage)
+	}
+
+	rb, err := a.client.Patch(ctx, key, patch, *version)
+	if err != nil {
+		return err
+	}
+
+	return a.print(subscriptionsResult(rb, *rb))
+}
+
+func runDelete(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("delete", "delete ID|SERVICE USER_ID [-if-match VERSION]\n       subsctl delete -from-file FILE")
+	version := fs.Int64("if-match", 0, "delete only if the record still has this version")
+	fromFile := fs.String("from-file", "", "delete every record of a file; records are matched by id or service_name and user_id, version is checked if set")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *fromFile != "" {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		return bulk(ctx, a, *fromFile, statusDeleted, func(key postgre.SubscriptionKey, rb postgre.RequestFields) (int64, error) {
+			return 0, a.client.Delete(ctx, key, rb.Version)
+		})
+	}
+
+	key, err := keyArg(args)
+	if err != nil {
+		return err
+	}
+	if err := a.client.Delete(ctx, key, *version); err != nil {
+		return err
+	}
+
+	res := bulkResult{Row: 1, Key: keyString(key), Status: statusDeleted}
+	return a.print(bulkResults([]bulkResult{res}))
+}
+
+// bulk выполняет fn для каждой записи файла по очереди и выводит результат по записям.
+// Ошибка одной записи не останавливает обработку остальных. fn возвращает новую версию записи, если она известна.
+func bulk(ctx context.Context, a *app, path, status string, fn func(postgre.SubscriptionKey, postgre.RequestFields) (int64, error)) error {
+	rows, err := readRows(path)
+	if err != nil {
+		return err
+	}
+
+	results := make([]bulkResult, 0, len(rows))
+	failed := 0
+	for i, rb := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		res := bulkResult{Row: i + 1, Status: status}
+
+		key, err := rowKey(rb)
+		if err == nil {
+			res.Key = keyString(key)
+			res.Version, err = fn(key, rb)
+		}
+		if err != nil {
+			res.Status, res.Error = statusFailed, errorMessage(err)
+			failed++
+		}
+
+		results = append(results, res)
+	}
+
+	if err := a.print(bulkResults(results)); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d failed", errPartial, failed, len(rows))
+	}
+	return nil
+}
+
+func bulkResults(results []bulkResult) result {
+	res := result{value: results, header: []string{"row", "key", "status", "version", "error"}}
+	for _, r := range results {
+		res.rows = append(res.rows, []string{strconv.Itoa(r.Row), r.Key, r.Status, formatID(r.Version), r.Error})
+	}
+	return res
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("list", "list [filters] [-limit N] [-cursor C | -all]")
+	var filter filterFlags
+	filter.register(fs)
+	limit := fs.Int("limit", 0, "page size (server default 50, max 1000)")
+	cursor := fs.String("cursor", "", "next_cursor of the previous page")
+	all := fs.Bool("all", false, "fetch all pages")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := noArgs(args); err != nil {
+		return err
+	}
+
+	if !*all {
+		page, err := a.client.List(ctx, filter.filter(), *limit, *cursor)
+		if err != nil {
+			return err
+		}
+		if err := a.print(subscriptionsResult(page, page.Subscriptions...)); err != nil {
+			return err
+		}
+		if page.NextCursor != "" && a.cfg.Output != outputJSON {
+			fmt.Fprintf(os.Stderr, "next page: -cursor %s\n", page.NextCursor)
+		}
+		return nil
+	}
+
+	var subs []postgre.RequestFields
+	next := *cursor
+	for {
+		page, err := a.client.List(ctx, filter.filter(), *limit, next)
+		if err != nil {
+			return err
+		}
+		subs = append(subs, page.Subscriptions...)
+		if page.NextCursor == "" {
+			break
+		}
+		next = page.NextCursor
+	}
+
+	return a.print(subscriptionsResult(subs, subs...))
+}
+
+func runExport(ctx context.Context, a *app, args []string) error {
+	fs := a.flagSet("export", "export [filters] [-format csv|ndjson]")
+	var filter filterFlags
+	filter.register(fs)
+	format := fs.String("format", client.ExportCSV, "export format: csv or ndjson; -output is ignored")
+
+	args, err := a.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := noArgs(args); err != nil {
+		return err
+	}
+
+	return a.client.Export(ctx, filter.filter(), *format, a.stdout)
+}
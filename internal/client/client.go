@@ -0,0 +1,246 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest_23.07.25/internal/http-server/response"
+)
+
+// Options - адрес API и учетные данные. Если заданы и APIKey, и Token, сервер проверяет ключ API.
+type Options struct {
+	// BaseURL - адрес сервиса без пути API, например http://localhost:8080.
+	BaseURL string
+	APIKey  string
+	// Token - JWT для заголовка Authorization: Bearer.
+	Token string
+	// Timeout ограничивает каждый запрос с JSON-ответом целиком, а для потоковой выгрузки - ожидание
+	// заголовков ответа: сама выгрузка может идти сколько угодно долго. 0 - без ограничения.
+	Timeout time.Duration
+}
+
+// Client вызывает HTTP API подписок.
+type Client struct {
+	base   *url.URL
+	opts   Options
+	client *http.Client
+}
+
+// New возвращает клиента API по адресу opts.BaseURL.
+func New(opts Options) (*Client, error) {
+	const op = "internal.client.New"
+
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("%s: base url is not set", op)
+	}
+
+	base, err := url.Parse(strings.TrimRight(opts.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid base url: %w", op, err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("%s: base url must start with http:// or https://", op)
+	}
+
+	return &Client{
+		base:   base,
+		opts:   opts,
+		client: &http.Client{},
+	}, nil
+}
+
+// Error - ошибка API в формате RFC 7807.
+type Error struct {
+	response.Problem
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %s", e.Status, e.Title)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Instance != "" {
+		msg += " (request " + e.Instance + ")"
+	}
+	for _, fe := range e.Errors {
+		if fe.Field != "" {
+			msg += fmt.Sprintf("\n  %s: %s", fe.Field, fe.Message)
+		}
+	}
+	return msg
+}
+
+// request - запрос к API.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	// body кодируется в JSON; contentType по умолчанию - application/json.
+	body        any
+	contentType string
+}
+
+// do выполняет запрос и декодирует JSON-ответ в out, если статус входит в ok.
+// Остальные статусы возвращаются как *Error. Возвращает заголовки ответа.
+func (c *Client) do(ctx context.Context, req request, out any, ok ...int) (http.Header, error) {
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if !slices.Contains(ok, resp.StatusCode) {
+		return nil, decodeError(resp)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return resp.Header, nil
+}
+
+// stream отправляет запрос, ответ на который читается потоком. Timeout ограничивает только ожидание
+// заголовков ответа; тело ограничено лишь ctx. Вызывающая сторона закрывает тело ответа.
+func (c *Client) stream(ctx context.Context, req request) (*http.Response, error) {
+	if c.opts.Timeout <= 0 {
+		return c.send(ctx, req)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(c.opts.Timeout, cancel)
+
+	resp, err := c.send(ctx, req)
+	if !timer.Stop() {
+		// Таймаут сработал, пока ждали заголовки: ctx уже отменен.
+		if err == nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("timeout awaiting response headers after %s", c.opts.Timeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody освобождает контекст запроса при закрытии тела ответа.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// send отправляет запрос с учетными данными и возвращает ответ с любым статусом.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	// Сегменты req.path уже экранированы вызывающей стороной.
+	u, err := url.Parse(c.base.String() + req.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build url: %w", err)
+	}
+	u.RawQuery = req.query.Encode()
+
+	var body io.Reader
+	contentType := req.contentType
+	if req.body != nil {
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if httpReq.Header.Get("Accept") == "" {
+		httpReq.Header.Set("Accept", "application/json")
+	}
+	if c.opts.APIKey != "" {
+		httpReq.Header.Set("X-API-Key", c.opts.APIKey)
+	}
+	if c.opts.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.opts.Token)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// decodeError читает ошибку API из ответа. Ответы не в формате RFC 7807 передаются как есть.
+func decodeError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == response.ProblemContentType || mediaType == "application/json" {
+		var p response.Problem
+		if err := json.Unmarshal(body, &p); err == nil && p.Status != 0 {
+			return &Error{Problem: p}
+		}
+	}
+
+	return &Error{Problem: response.Problem{
+		Status: resp.StatusCode,
+		Title:  http.StatusText(resp.StatusCode),
+		Detail: strings.TrimSpace(string(body)),
+	}}
+}
+
+// ifMatch возвращает заголовок If-Match для версии записи; 0 - без проверки версии.
+func ifMatch(version int64) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {`"` + strconv.FormatInt(version, 10) + `"`}}
+}
+
+// etagVersion возвращает версию записи из заголовка ETag или 0.
+func etagVersion(h http.Header) int64 {
+	v, err := strconv.ParseInt(strings.Trim(h.Get("ETag"), `"`), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
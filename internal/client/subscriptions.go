@@ -0,0 +1,359 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gotest_23.07.25/internal/health"
+	"gotest_23.07.25/internal/http-server/handlers"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// api paths:
+const (
+	pathSubscriptions = "/api/v1/subscriptions"
+	pathBatch         = "/api/v1/subscriptions:batch"
+	pathExport        = "/api/v1/subscriptions/export"
+	pathRangePrice    = "/api/v1/subscriptions/range-price"
+	pathReport        = "/api/v1/subscriptions/report"
+	pathKeys          = "/api/v1/admin/keys"
+	pathLiveness      = "/healthz"
+	pathReadiness     = "/readyz"
+)
+
+// batch modes:
+const (
+	BatchAtomic = "atomic"
+	BatchPerRow = "per_row"
+)
+
+// export formats:
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+// Filter - фильтры и сортировка списка и выгрузки подписок. Пустые поля не фильтруют.
+type Filter struct {
+	ServiceName string
+	UserID      string
+	// ActiveOn - дата YYYY-MM-DD, на которую подписка активна.
+	ActiveOn string
+	PriceMin *uint16
+	PriceMax *uint16
+	// Sort - поле сортировки: price, start_date, service_name; префикс "-" - по убыванию.
+	Sort string
+}
+
+func (f Filter) query() url.Values {
+	q := url.Values{}
+	set := func(k, v string) {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	set("service_name", f.ServiceName)
+	set("user_id", f.UserID)
+	set("active_on", f.ActiveOn)
+	set("sort", f.Sort)
+	if f.PriceMin != nil {
+		q.Set("price_min", strconv.FormatUint(uint64(*f.PriceMin), 10))
+	}
+	if f.PriceMax != nil {
+		q.Set("price_max", strconv.FormatUint(uint64(*f.PriceMax), 10))
+	}
+	return q
+}
+
+// Patch - изменяемые поля подписки; nil-поля не меняются. ClearEndDate снимает дату окончания.
+type Patch struct {
+	Price        *uint16
+	StartDate    *time.Time
+	EndDate      *time.Time
+	ClearEndDate bool
+}
+
+// body возвращает тело JSON Merge Patch (RFC 7396): null снимает дату окончания.
+func (p Patch) body() map[string]any {
+	body := make(map[string]any)
+	if p.Price != nil {
+		body["price"] = *p.Price
+	}
+	if p.StartDate != nil {
+		body["start_date"] = p.StartDate.Format(time.RFC3339)
+	}
+	switch {
+	case p.ClearEndDate:
+		body["end_date"] = nil
+	case p.EndDate != nil:
+		body["end_date"] = p.EndDate.Format(time.RFC3339)
+	}
+	return body
+}
+
+// subscriptionPath возвращает путь подписки: по ID, если он задан, иначе по имени сервиса и ID пользователя.
+func subscriptionPath(key postgre.SubscriptionKey) string {
+	if key.ID != 0 {
+		return pathSubscriptions + "/" + strconv.FormatInt(key.ID, 10)
+	}
+	return pathSubscriptions + "/" + url.PathEscape(key.ServiceName) + "/" + url.PathEscape(key.UserID)
+}
+
+// Create создает подписку и возвращает ее с присвоенным ID.
+// Непустой idempotencyKey передается в заголовке Idempotency-Key.
+func (c *Client) Create(ctx context.Context, rb postgre.RequestFields, idempotencyKey string) (*postgre.RequestFields, error) {
+	const op = "internal.client.Create"
+
+	var resp response.Response
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   pathSubscriptions,
+		header: idempotency(idempotencyKey),
+		body:   rb,
+	}, &resp, http.StatusOK, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &resp.Fields, nil
+}
+
+// CreateBatch создает подписки одним запросом в режиме mode (atomic или per_row).
+// Откат пакета в режиме atomic не считается ошибкой: результат по строкам есть в ответе.
+func (c *Client) CreateBatch(ctx context.Context, rows []postgre.RequestFields, mode, idempotencyKey string) (*handlers.BatchResponse, error) {
+	const op = "internal.client.CreateBatch"
+
+	var resp handlers.BatchResponse
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   pathBatch,
+		query:  url.Values{"mode": {mode}},
+		header: idempotency(idempotencyKey),
+		body:   rows,
+	}, &resp, http.StatusOK, http.StatusUnprocessableEntity)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &resp, nil
+}
+
+// Get возвращает подписку по ключу.
+func (c *Client) Get(ctx context.Context, key postgre.SubscriptionKey) (*postgre.RequestFields, error) {
+	const op = "internal.client.Get"
+
+	var resp response.Response
+	header, err := c.do(ctx, request{method: http.MethodGet, path: subscriptionPath(key)}, &resp, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if resp.Fields.Version == 0 {
+		resp.Fields.Version = etagVersion(header)
+	}
+
+	return &resp.Fields, nil
+}
+
+// Update заменяет цену и даты подписки и возвращает новую версию записи.
+// Ненулевой version передается в If-Match: запись меняется, только если ее версия не изменилась.
+func (c *Client) Update(ctx context.Context, key postgre.SubscriptionKey, fields postgre.RequestUpdateFields, version int64) (int64, error) {
+	const op = "internal.client.Update"
+
+	header, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   subscriptionPath(key),
+		header: ifMatch(version),
+		body:   fields,
+	}, nil, http.StatusOK)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return etagVersion(header), nil
+}
+
+// Patch меняет отдельные поля подписки и возвращает запись после изменения.
+func (c *Client) Patch(ctx context.Context, key postgre.SubscriptionKey, patch Patch, version int64) (*postgre.RequestFields, error) {
+	const op = "internal.client.Patch"
+
+	var resp response.Response
+	_, err := c.do(ctx, request{
+		method:      http.MethodPatch,
+		path:        subscriptionPath(key),
+		header:      ifMatch(version),
+		body:        patch.body(),
+		contentType: "application/merge-patch+json",
+	}, &resp, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &resp.Fields, nil
+}
+
+// Delete удаляет подписку. Ненулевой version передается в If-Match.
+func (c *Client) Delete(ctx context.Context, key postgre.SubscriptionKey, version int64) error {
+	const op = "internal.client.Delete"
+
+	_, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   subscriptionPath(key),
+		header: ifMatch(version),
+	}, nil, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// List возвращает страницу подписок размером limit (0 - размер по умолчанию), начиная после cursor.
+func (c *Client) List(ctx context.Context, filter Filter, limit int, cursor string) (*handlers.ListResponse, error) {
+	const op = "internal.client.List"
+
+	q := filter.query()
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+
+	var resp handlers.ListResponse
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: pathSubscriptions, query: q}, &resp, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &resp, nil
+}
+
+// Export записывает в w выгрузку подписок в формате format (csv или ndjson) по мере получения.
+func (c *Client) Export(ctx context.Context, filter Filter, format string, w io.Writer) error {
+	const op = "internal.client.Export"
+
+	q := filter.query()
+	q.Set("format", format)
+
+	resp, err := c.stream(ctx, request{
+		method: http.MethodGet,
+		path:   pathExport,
+		query:  q,
+		header: http.Header{"Accept": {"*/*"}},
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %w", op, decodeError(resp))
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RangePrice возвращает суммарную стоимость подписок за период.
+func (c *Client) RangePrice(ctx context.Context, rb handlers.RangeRequestBody) (uint64, error) {
+	const op = "internal.client.RangePrice"
+
+	var resp handlers.RangeResponse
+	_, err := c.do(ctx, request{method: http.MethodPost, path: pathRangePrice, body: rb}, &resp, http.StatusOK)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp.Price, nil
+}
+
+// Report возвращает расходы на подписки за период с группировкой.
+func (c *Client) Report(ctx context.Context, rb handlers.ReportRequestBody) (*handlers.ReportResponse, error) {
+	const op = "internal.client.Report"
+
+	var resp handlers.ReportResponse
+	_, err := c.do(ctx, request{method: http.MethodPost, path: pathReport, body: rb}, &resp, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &resp, nil
+}
+
+// IssueKey выпускает ключ API. Ключ целиком есть только в этом ответе.
+func (c *Client) IssueKey(ctx context.Context, rb handlers.IssueKeyRequest) (*handlers.IssueKeyResponse, error) {
+	const op = "internal.client.IssueKey"
+
+	var resp handlers.IssueKeyResponse
+	_, err := c.do(ctx, request{method: http.MethodPost, path: pathKeys, body: rb}, &resp, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &resp, nil
+}
+
+// ListKeys возвращает все ключи API, включая отозванные.
+func (c *Client) ListKeys(ctx context.Context) ([]postgre.APIKey, error) {
+	const op = "internal.client.ListKeys"
+
+	var resp handlers.ListKeysResponse
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: pathKeys}, &resp, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp.Keys, nil
+}
+
+// RevokeKey отзывает ключ API.
+func (c *Client) RevokeKey(ctx context.Context, id int64) error {
+	const op = "internal.client.RevokeKey"
+
+	path := pathKeys + "/" + strconv.FormatInt(id, 10)
+	if _, err := c.do(ctx, request{method: http.MethodDelete, path: path}, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Health проверяет, что процесс сервиса жив.
+func (c *Client) Health(ctx context.Context) error {
+	const op = "internal.client.Health"
+
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: pathLiveness}, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Ready возвращает результат проверки готовности. Неготовность сервиса не считается ошибкой.
+func (c *Client) Ready(ctx context.Context) (*health.Report, error) {
+	const op = "internal.client.Ready"
+
+	var report health.Report
+	_, err := c.do(ctx, request{method: http.MethodGet, path: pathReadiness}, &report, http.StatusOK, http.StatusServiceUnavailable)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &report, nil
+}
+
+// idempotency возвращает заголовок Idempotency-Key; пустой ключ - без заголовка.
+func idempotency(key string) http.Header {
+	if key == "" {
+		return nil
+	}
+	return http.Header{"Idempotency-Key": {key}}
+}
@@ -10,10 +10,38 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+// sql drivers:
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 type Config struct {
 	Env         string       `yaml:"env" env:"ENV" env-default:"local" env-requered:"true"`
+	Storage     *Storage     `yaml:"storage"`
 	StorageLink *StorageLink `yaml:"storage_link"`
 	HTTPServer  *HTTPServer  `yaml:"http_server"`
+	GRPCServer  *GRPCServer  `yaml:"grpc_server"`
+	Idempotency *Idempotency `yaml:"idempotency"`
+	Auth        *Auth        `yaml:"auth"`
+	JWT         *JWT         `yaml:"jwt"`
+	RateLimit   *RateLimit   `yaml:"rate_limit"`
+	Metrics     *Metrics     `yaml:"metrics"`
+	Tracing     *Tracing     `yaml:"tracing"`
+	Health      *Health      `yaml:"health"`
+}
+
+type Storage struct {
+	// Backend - sql или memory; по умолчанию sql.
+	Backend string `yaml:"backend"`
+	// QueryTimeout ограничивает время одной операции хранилища; 0 - значение по умолчанию,
+	// отрицательное - без ограничения, кроме контекста запроса.
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// ExportTimeout ограничивает время выгрузки Export (/subscriptions/export и ListSubscriptions gRPC); 0 - значение по умолчанию,
+	// отрицательное - без ограничения, кроме контекста запроса.
+	ExportTimeout time.Duration `yaml:"export_timeout"`
+	// AutoMigrate - применять все новые миграции при запуске serve; иначе схема обновляется командой migrate up.
+	AutoMigrate bool `yaml:"auto_migrate" env:"AUTO_MIGRATE"`
 }
 
 type StorageLink struct {
@@ -32,6 +60,130 @@ type HTTPServer struct {
 	Idle_timeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 }
 
+// GRPCServer - настройки gRPC-сервера. Без секции grpc_server gRPC-сервер не запускается.
+type GRPCServer struct {
+	Enabled bool   `yaml:"enabled" env:"GRPC_ENABLED"`
+	Address string `yaml:"address" env:"GRPC_ADDRESS" env-default:":9090"`
+	// Reflection - регистрировать сервис reflection для grpcurl и подобных клиентов.
+	Reflection bool `yaml:"reflection"`
+}
+
+// DefaultGRPCAddress - адрес gRPC-сервера, если он не задан.
+const DefaultGRPCAddress = ":9090"
+
+// Idempotency - настройки middleware Idempotency-Key.
+type Idempotency struct {
+	// TTL - время хранения ответов; 0 - DefaultIdempotencyTTL.
+	TTL time.Duration `yaml:"ttl"`
+	// MaxEntries - наибольшее число сохраненных ответов; 0 - DefaultIdempotencyMaxEntries.
+	MaxEntries int `yaml:"max_entries"`
+	// MaxBytes - наибольший суммарный размер тел сохраненных ответов; 0 - DefaultIdempotencyMaxBytes.
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+// Auth - настройки аутентификации по ключам API.
+// Без секции auth аутентификация выключена.
+type Auth struct {
+	Enabled bool `yaml:"enabled" env:"AUTH_ENABLED"`
+	// AdminKey - ключ с областью admin для выпуска первых ключей; пустой - вход по нему запрещен.
+	AdminKey string `yaml:"admin_key" env:"API_ADMIN_KEY"`
+}
+
+// JWT - настройки аутентификации по JWT в заголовке Authorization: Bearer.
+// Без секции jwt токены не принимаются.
+type JWT struct {
+	Enabled bool `yaml:"enabled" env:"JWT_ENABLED"`
+	// Secret - ключ HS256.
+	Secret string `yaml:"secret" env:"JWT_SECRET"`
+	// JWKSFile - путь к файлу JWKS с открытыми ключами RS256/ES256.
+	JWKSFile  string        `yaml:"jwks_file" env:"JWT_JWKS_FILE"`
+	Issuer    string        `yaml:"issuer"`
+	Audience  string        `yaml:"audience"`
+	UserClaim string        `yaml:"user_claim" env-default:"sub"`
+	RoleClaim string        `yaml:"role_claim" env-default:"roles"`
+	AdminRole string        `yaml:"admin_role" env-default:"admin"`
+	Leeway    time.Duration `yaml:"leeway" env-default:"30s"`
+}
+
+// RateLimit - лимиты запросов по группам маршрутов. Без секции rate_limit запросы не ограничиваются.
+type RateLimit struct {
+	// TrustedProxies - адреса и подсети прокси, которым доверяется X-Forwarded-For.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// Groups - лимиты по группам маршрутов: subscriptions_read, subscriptions_write, reports, admin,
+	// и auth - лимит по IP для всех запросов до проверки учетных данных.
+	Groups map[string]RateLimitGroup `yaml:"groups"`
+}
+
+// RateLimitGroup - лимиты одной группы маршрутов.
+type RateLimitGroup struct {
+	// Rate - запросов в секунду на клиента; 0 - без ограничения частоты.
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+	// KeyBy - как различать клиентов: ip, api_key или user.
+	KeyBy string `yaml:"key_by"`
+	// MaxInFlight - одновременных запросов группы от всех клиентов; 0 - без ограничения.
+	MaxInFlight int `yaml:"max_in_flight"`
+}
+
+// Metrics - настройки эндпоинта метрик Prometheus. Без секции metrics метрики выключены.
+type Metrics struct {
+	Enabled bool   `yaml:"enabled" env:"METRICS_ENABLED"`
+	Path    string `yaml:"path" env-default:"/metrics"`
+}
+
+// DefaultMetricsPath - путь эндпоинта метрик, если он не задан.
+const DefaultMetricsPath = "/metrics"
+
+// Tracing - настройки трассировки OpenTelemetry. Без секции tracing трассировка выключена.
+type Tracing struct {
+	Enabled     bool   `yaml:"enabled" env:"TRACING_ENABLED"`
+	ServiceName string `yaml:"service_name" env-default:"subscriptions"`
+	// Exporter - otlp, stdout или file.
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"stdout"`
+	// OTLPEndpoint - адрес коллектора OTLP/HTTP (host:port).
+	OTLPEndpoint string `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	OTLPInsecure bool   `yaml:"otlp_insecure"`
+	// File - файл спанов для экспортера file.
+	File        string  `yaml:"file" env-default:"traces.json"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
+// Health - настройки проверки готовности /readyz и остановки сервера.
+// Незаданные поля, кроме DrainDelay, получают значения по умолчанию.
+type Health struct {
+	// Timeout ограничивает время всех проверок одного запроса /readyz.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxPingLatency - задержка ping БД, после которой сервис считается неготовым.
+	MaxPingLatency time.Duration `yaml:"max_ping_latency"`
+	// MaxPoolSaturation - доля занятых соединений пула БД, после которой сервис считается неготовым.
+	MaxPoolSaturation float64 `yaml:"max_pool_saturation"`
+	// DrainDelay - пауза между отказом /readyz и началом остановки HTTP-сервера,
+	// за которую балансировщик успевает перестать отправлять запросы; 0 - без паузы.
+	DrainDelay time.Duration `yaml:"drain_delay"`
+}
+
+// storage defaults:
+const (
+	DefaultStorageBackend = "sql"
+	DefaultQueryTimeout   = 3 * time.Second
+	DefaultExportTimeout  = 5 * time.Minute
+)
+
+// health defaults:
+const (
+	DefaultHealthTimeout           = 2 * time.Second
+	DefaultHealthMaxPingLatency    = 500 * time.Millisecond
+	DefaultHealthMaxPoolSaturation = 0.9
+	DefaultHealthDrainDelay        = 5 * time.Second
+)
+
+// idempotency defaults:
+const (
+	DefaultIdempotencyTTL        = 24 * time.Hour
+	DefaultIdempotencyMaxEntries = 100000
+	DefaultIdempotencyMaxBytes   = 256 << 20
+)
+
 func MustLoad() *Config {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
@@ -48,10 +200,89 @@ func MustLoad() *Config {
 		log.Fatalf("failed to read config file: %s", err)
 	}
 
+	if cfg.Storage == nil {
+		cfg.Storage = &Storage{}
+	}
+	if cfg.Storage.Backend == "" {
+		cfg.Storage.Backend = DefaultStorageBackend
+	}
+	if cfg.Storage.QueryTimeout == 0 {
+		cfg.Storage.QueryTimeout = DefaultQueryTimeout
+	}
+	if cfg.Storage.ExportTimeout == 0 {
+		cfg.Storage.ExportTimeout = DefaultExportTimeout
+	}
+
+	if cfg.GRPCServer == nil {
+		cfg.GRPCServer = &GRPCServer{}
+	}
+	if cfg.GRPCServer.Address == "" {
+		cfg.GRPCServer.Address = DefaultGRPCAddress
+	}
+
+	if cfg.Idempotency == nil {
+		cfg.Idempotency = &Idempotency{}
+	}
+	if cfg.Idempotency.TTL == 0 {
+		cfg.Idempotency.TTL = DefaultIdempotencyTTL
+	}
+	if cfg.Idempotency.MaxEntries == 0 {
+		cfg.Idempotency.MaxEntries = DefaultIdempotencyMaxEntries
+	}
+	if cfg.Idempotency.MaxBytes == 0 {
+		cfg.Idempotency.MaxBytes = DefaultIdempotencyMaxBytes
+	}
+
+	if cfg.Auth == nil {
+		cfg.Auth = &Auth{}
+	}
+
+	if cfg.JWT == nil {
+		cfg.JWT = &JWT{}
+	}
+
+	if cfg.RateLimit == nil {
+		cfg.RateLimit = &RateLimit{}
+	}
+
+	if cfg.Tracing == nil {
+		cfg.Tracing = &Tracing{}
+	}
+
+	if cfg.Health == nil {
+		cfg.Health = &Health{DrainDelay: DefaultHealthDrainDelay}
+	}
+	if cfg.Health.Timeout == 0 {
+		cfg.Health.Timeout = DefaultHealthTimeout
+	}
+	if cfg.Health.MaxPingLatency == 0 {
+		cfg.Health.MaxPingLatency = DefaultHealthMaxPingLatency
+	}
+	if cfg.Health.MaxPoolSaturation == 0 {
+		cfg.Health.MaxPoolSaturation = DefaultHealthMaxPoolSaturation
+	}
+
+	if cfg.Metrics == nil {
+		cfg.Metrics = &Metrics{}
+	}
+	if cfg.Metrics.Path == "" {
+		cfg.Metrics.Path = DefaultMetricsPath
+	}
+
 	return &cfg
 }
 
+// GetStorageLink возвращает строку подключения к БД.
+// Для sqlite это путь к файлу БД из sql_dbname, для postgres - URL подключения.
 func GetStorageLink(cfg *Config) string {
+	if cfg.StorageLink.SQLDriver == DriverSQLite {
+		slog.Debug("Storage link set in config",
+			slog.String("SQLDriver", cfg.StorageLink.SQLDriver),
+			slog.String("SQLDBName", cfg.StorageLink.SQLDBName),
+		)
+		return cfg.StorageLink.SQLDBName
+	}
+
 	storageLink := fmt.Sprintf("%s://%s:%s@%s:%s/%s?sslmode=%s",
 		cfg.StorageLink.SQLDriver,
 		cfg.StorageLink.SQLUser,
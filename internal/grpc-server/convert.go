@@ -0,0 +1,87 @@
+package grpcserver
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	subscriptionsv1 "gotest_23.07.25/api/subscriptions/v1"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+)
+
+var errInvalidKey = errors.New("key must have a positive id or both service_name and user_id")
+
+// toProto возвращает запись о подписке в виде сообщения API.
+func toProto(rb postgre.RequestFields) *subscriptionsv1.Subscription {
+	s := &subscriptionsv1.Subscription{
+		Id:          rb.ID,
+		ServiceName: rb.ServiceName,
+		Price:       uint32(rb.Price),
+		UserId:      rb.UserId,
+		StartDate:   timestamppb.New(rb.StartDate),
+		Version:     rb.Version,
+	}
+	if rb.EndDate != nil {
+		s.EndDate = timestamppb.New(*rb.EndDate)
+	}
+	return s
+}
+
+// subscriptionFields возвращает поля новой записи из сообщения API. id и version переносятся, чтобы
+// validation.Subscription отклонил их: их назначает сервер.
+func subscriptionFields(s *subscriptionsv1.Subscription) (postgre.RequestFields, validation.Errors) {
+	price, errs := priceValue("price", s.GetPrice())
+	return postgre.RequestFields{
+		ID:          s.GetId(),
+		ServiceName: s.GetServiceName(),
+		Price:       price,
+		UserId:      s.GetUserId(),
+		StartDate:   timeValue(s.GetStartDate()),
+		EndDate:     timePtr(s.GetEndDate()),
+		Version:     s.GetVersion(),
+	}, errs
+}
+
+// subscriptionKey возвращает ключ подписки: id, если он задан, иначе пару service_name и user_id.
+func subscriptionKey(k *subscriptionsv1.SubscriptionKey) (postgre.SubscriptionKey, error) {
+	switch {
+	case k.GetId() > 0:
+		return postgre.SubscriptionKey{ID: k.GetId()}, nil
+	case k.GetId() < 0 || k.GetServiceName() == "" || k.GetUserId() == "":
+		return postgre.SubscriptionKey{}, errInvalidKey
+	default:
+		return postgre.SubscriptionKey{ServiceName: k.GetServiceName(), UserID: k.GetUserId()}, nil
+	}
+}
+
+// priceValue проверяет, что цена помещается в uint16, как в HTTP API. Остальные проверки цены - в validation.Price.
+// Для слишком большой цены вместе с ошибкой возвращается MaxUint16, чтобы validation.Price не добавил вторую ошибку поля.
+func priceValue(field string, v uint32) (uint16, validation.Errors) {
+	if v > math.MaxUint16 {
+		return math.MaxUint16, validation.Errors{{
+			Field:   field,
+			Code:    validation.CodeInvalidValue,
+			Message: field + " must be at most 65535",
+		}}
+	}
+	return uint16(v), nil
+}
+
+// timeValue возвращает время из timestamp; для пустого timestamp - нулевое время.
+func timeValue(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// timePtr возвращает время из timestamp или nil для пустого timestamp.
+func timePtr(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
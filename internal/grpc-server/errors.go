@@ -0,0 +1,93 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// errForeignSubscription - сообщение PERMISSION_DENIED при обращении к подпискам другого пользователя.
+const errForeignSubscription = "subscription belongs to another user"
+
+// validationError возвращает INVALID_ARGUMENT со списком ошибок валидации в google.rpc.BadRequest.
+func validationError(log *slog.Logger, errs validation.Errors) error {
+	log.Info("Invalid request", slog.String("error", errs.Error()))
+
+	br := &errdetails.BadRequest{}
+	for _, fe := range errs {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       fe.Field,
+			Description: fe.Message,
+			Reason:      fe.Code,
+		})
+	}
+
+	st := status.New(codes.InvalidArgument, errs.Error())
+	if withDetails, err := st.WithDetails(br); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// invalidArgument возвращает INVALID_ARGUMENT с сообщением msg.
+func invalidArgument(log *slog.Logger, msg string) error {
+	log.Info("Invalid request", slog.String("error", msg))
+	return status.Error(codes.InvalidArgument, msg)
+}
+
+// forbidden возвращает PERMISSION_DENIED на обращение к данным другого пользователя.
+func forbidden(log *slog.Logger, msg string) error {
+	log.Info("Access to another user's data denied", slog.String("detail", msg))
+	return status.Error(codes.PermissionDenied, msg)
+}
+
+// storageError возвращает код gRPC по категории ошибки хранилища, как writeStorageError в HTTP API:
+// NOT_FOUND, ALREADY_EXISTS, ABORTED, INVALID_ARGUMENT или UNAVAILABLE. Вызовы, прерванные клиентом,
+// получают CANCELED или DEADLINE_EXCEEDED, прерванные по таймауту хранилища - UNAVAILABLE.
+// Ошибки без категории логируются с сообщением msg и возвращаются как INTERNAL.
+func storageError(ctx context.Context, log *slog.Logger, err error, msg string) error {
+	reason, _ := postgre.ErrorReason(err)
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		if ctx.Err() != nil {
+			log.Info("Request canceled by client", slog.String("error", err.Error()))
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		log.Error("Storage operation timed out", slog.String("error", err.Error()))
+		return status.Error(codes.Unavailable, "storage operation timed out")
+	case errors.Is(err, postgre.ErrVersionMismatch):
+		log.Info("Precondition failed", slog.String("error", err.Error()))
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, postgre.ErrSubscriptionExists):
+		log.Info("Subscription period overlaps", slog.String("error", err.Error()))
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, postgre.ErrNotFound):
+		log.Warn("Record not found", slog.String("error", err.Error()))
+		return status.Error(codes.NotFound, reason)
+	case errors.Is(err, postgre.ErrInvalidPeriod):
+		return validationError(log, validation.Errors{{
+			Field:   "end_date",
+			Code:    validation.CodeEndBeforeStart,
+			Message: "end_date cannot be before start_date",
+		}})
+	case errors.Is(err, postgre.ErrInvalidInput):
+		log.Info("Invalid input", slog.String("error", err.Error()))
+		return status.Error(codes.InvalidArgument, reason)
+	case errors.Is(err, postgre.ErrConflict):
+		log.Info("Conflict", slog.String("error", err.Error()))
+		return status.Error(codes.AlreadyExists, reason)
+	case errors.Is(err, postgre.ErrUnavailable):
+		log.Error("Storage unavailable", slog.String("error", err.Error()))
+		return status.Error(codes.Unavailable, reason)
+	default:
+		log.Error(msg, slog.String("error", err.Error()))
+		return status.Error(codes.Internal, "")
+	}
+}
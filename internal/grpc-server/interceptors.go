@@ -0,0 +1,408 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	subscriptionsv1 "gotest_23.07.25/api/subscriptions/v1"
+	"gotest_23.07.25/internal/http-server/middlewares/auth"
+	"gotest_23.07.25/internal/http-server/middlewares/ratelimit"
+	"gotest_23.07.25/internal/lib/apikey"
+	"gotest_23.07.25/internal/metrics"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// metadata keys:
+const (
+	metadataKey           = "x-api-key"
+	metadataAuthorization = "authorization"
+)
+
+// bearerPrefix - схема значения authorization для JWT.
+const bearerPrefix = "Bearer "
+
+// methodScopes - области доступа методов SubscriptionService.
+// Методы других сервисов (grpc.health.v1, reflection) доступны без учетных данных.
+var methodScopes = map[string]string{
+	subscriptionsv1.SubscriptionService_CreateSubscription_FullMethodName: apikey.ScopeSubscriptionsWrite,
+	subscriptionsv1.SubscriptionService_GetSubscription_FullMethodName:    apikey.ScopeSubscriptionsRead,
+	subscriptionsv1.SubscriptionService_UpdateSubscription_FullMethodName: apikey.ScopeSubscriptionsWrite,
+	subscriptionsv1.SubscriptionService_DeleteSubscription_FullMethodName: apikey.ScopeSubscriptionsWrite,
+	subscriptionsv1.SubscriptionService_ListSubscriptions_FullMethodName:  apikey.ScopeSubscriptionsRead,
+	subscriptionsv1.SubscriptionService_GetRangePrice_FullMethodName:      apikey.ScopeReportsRead,
+}
+
+// wrappedStream подменяет контекст потока.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedStream) Context() context.Context {
+	return s.ctx
+}
+
+func withContext(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &wrappedStream{ServerStream: ss, ctx: ctx}
+}
+
+type attrsKey struct{}
+
+// addLogAttrs добавляет атрибуты к записи "request completed" текущего вызова.
+func addLogAttrs(ctx context.Context, attrs ...slog.Attr) {
+	if extra, ok := ctx.Value(attrsKey{}).(*[]slog.Attr); ok {
+		*extra = append(*extra, attrs...)
+	}
+}
+
+// logCall выполняет call и пишет в лог метод, код ответа и длительность вызова.
+func logCall(ctx context.Context, log *slog.Logger, method string, call func(ctx context.Context) error) error {
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+	}
+
+	entry := log.With(
+		slog.String("method", method),
+		slog.String("remote_addr", remote),
+		tracing.LogAttr(ctx),
+	)
+
+	var extra []slog.Attr
+	ctx = context.WithValue(ctx, attrsKey{}, &extra)
+
+	t1 := time.Now()
+	err := call(ctx)
+
+	entry.LogAttrs(ctx, slog.LevelInfo, "request completed", append([]slog.Attr{
+		slog.String("code", status.Code(err).String()),
+		slog.String("duration", time.Since(t1).String()),
+	}, extra...)...)
+
+	return err
+}
+
+func logUnary(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		err = logCall(ctx, log, info.FullMethod, func(ctx context.Context) error {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+func logStream(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return logCall(ss.Context(), log, info.FullMethod, func(ctx context.Context) error {
+			return handler(srv, withContext(ss, ctx))
+		})
+	}
+}
+
+// recoverCall выполняет call и возвращает панику в нем как INTERNAL.
+func recoverCall(log *slog.Logger, method string, call func() error) (err error) {
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			log.Error("panic in gRPC handler",
+				slog.String("method", method),
+				slog.Any("panic", rvr),
+				slog.String("stack", string(debug.Stack())),
+			)
+			err = status.Error(codes.Internal, "")
+		}
+	}()
+	return call()
+}
+
+func recoverUnary(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		err = recoverCall(log, info.FullMethod, func() error {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+func recoverStream(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return recoverCall(log, info.FullMethod, func() error {
+			return handler(srv, ss)
+		})
+	}
+}
+
+// metadataCarrier читает контекст трассировки из метаданных вызова.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// traceCall выполняет call в серверном спане, названном по методу. Родительский спан
+// берется из метаданных traceparent.
+func traceCall(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	ctx, span := tracing.Tracer().Start(ctx, service+"/"+name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", name),
+		),
+	)
+	defer span.End()
+
+	err := call(ctx)
+
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+
+	return err
+}
+
+func traceUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		err = traceCall(ctx, info.FullMethod, func(ctx context.Context) error {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+func traceStream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return traceCall(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+			return handler(srv, withContext(ss, ctx))
+		})
+	}
+}
+
+// authenticate проверяет учетные данные вызова так же, как middleware auth HTTP API: ключ API
+// в метаданных x-api-key или JWT в authorization: Bearer. Если переданы оба, проверяется ключ API.
+// Возвращает контекст с клиентом, у которого есть область доступа метода.
+func authenticate(ctx context.Context, log *slog.Logger, opts auth.Options, method string) (context.Context, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		if strings.HasPrefix(method, "/"+subscriptionsv1.SubscriptionService_ServiceDesc.ServiceName+"/") {
+			log.Error("No scope for method", slog.String("method", method))
+			return nil, status.Error(codes.PermissionDenied, "method has no access scope")
+		}
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	key := first(metadataKey)
+	bearer, hasBearer := strings.CutPrefix(first(metadataAuthorization), bearerPrefix)
+
+	var (
+		id  auth.Identity
+		err error
+	)
+	switch {
+	case key != "" && opts.Keys != nil:
+		id, err = auth.IdentifyKey(ctx, opts, key)
+		if errors.Is(err, postgre.ErrNotFound) {
+			log.Info("Invalid API key", slog.String("method", method))
+			return nil, status.Error(codes.Unauthenticated, "invalid or revoked API key")
+		}
+		if err != nil {
+			log.Error("Failed to look up API key", slog.String("method", method), slog.String("error", err.Error()))
+			return nil, status.Error(codes.Unavailable, "cannot verify API key")
+		}
+	case hasBearer && opts.Tokens != nil:
+		id, err = auth.IdentifyToken(opts, bearer)
+		if err != nil {
+			log.Info("Invalid token", slog.String("method", method), slog.String("error", err.Error()))
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	default:
+		return nil, status.Error(codes.Unauthenticated, "missing credentials: "+accepted(opts))
+	}
+
+	attrs := []slog.Attr{slog.String("auth_subject", id.Subject())}
+	if id.Token {
+		attrs = append(attrs, slog.String("user_id", id.Name), slog.Bool("admin", id.UserID == ""))
+	} else {
+		attrs = append(attrs, slog.Int64("api_key_id", id.KeyID), slog.String("api_key_name", id.Name))
+	}
+	addLogAttrs(ctx, attrs...)
+
+	if !apikey.HasScope(id.Scopes, scope) {
+		return nil, status.Error(codes.PermissionDenied, "credentials have no "+scope+" scope")
+	}
+
+	return auth.WithIdentity(ctx, id), nil
+}
+
+// accepted описывает, какие учетные данные ожидаются, для ответа UNAUTHENTICATED.
+func accepted(opts auth.Options) string {
+	switch {
+	case opts.Keys != nil && opts.Tokens != nil:
+		return metadataKey + " metadata or bearer token is required"
+	case opts.Tokens != nil:
+		return "bearer token is required"
+	default:
+		return metadataKey + " metadata is required"
+	}
+}
+
+func authUnary(log *slog.Logger, opts auth.Options) grpc.UnaryServerInterceptor {
+	log = log.With(slog.String("component", "grpc-server/auth"))
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, log.With(tracing.LogAttr(ctx)), opts, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authStream(log *slog.Logger, opts auth.Options) grpc.StreamServerInterceptor {
+	log = log.With(slog.String("component", "grpc-server/auth"))
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), log.With(tracing.LogAttr(ss.Context())), opts, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, withContext(ss, ctx))
+	}
+}
+
+// metricsCall выполняет call и учитывает его в метриках gRPC по методу и коду ответа.
+func metricsCall(m *metrics.Metrics, method string, call func() error) error {
+	m.GRPCInFlight.Inc()
+	defer m.GRPCInFlight.Dec()
+
+	t1 := time.Now()
+	err := call()
+
+	labels := []string{method, status.Code(err).String()}
+	m.GRPCRequests.WithLabelValues(labels...).Inc()
+	m.GRPCDuration.WithLabelValues(labels...).Observe(time.Since(t1).Seconds())
+
+	return err
+}
+
+func metricsUnary(m *metrics.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		err = metricsCall(m, info.FullMethod, func() error {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+func metricsStream(m *metrics.Metrics) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return metricsCall(m, info.FullMethod, func() error {
+			return handler(srv, ss)
+		})
+	}
+}
+
+// limitCall применяет к вызову лимиты группы метода так же, как middleware ratelimit HTTP API:
+// частоту вызовов клиента, определенного по ключу API, пользователю или IP, и число одновременных
+// вызовов группы. Отклоненные вызовы получают RESOURCE_EXHAUSTED. Методы без группы не ограничиваются.
+func limitCall(ctx context.Context, log *slog.Logger, limits map[string]*ratelimit.Limiter, method string, call func() error) error {
+	l, ok := limits[method]
+	if !ok {
+		return call()
+	}
+
+	if l.Options().Rate > 0 {
+		var ip string
+		if p, ok := peer.FromContext(ctx); ok {
+			ip = p.Addr.String()
+			if host, _, err := net.SplitHostPort(ip); err == nil {
+				ip = host
+			}
+		}
+
+		client := l.Client(ctx, ip)
+		if _, _, wait := l.Take(client); wait > 0 {
+			log.Info("Rate limit exceeded", slog.String("method", method), slog.String("client", client))
+			return status.Error(codes.ResourceExhausted, "rate limit exceeded, retry in "+ratelimit.Seconds(wait)+"s")
+		}
+	}
+
+	release, ok := l.Acquire()
+	if !ok {
+		log.Warn("In-flight limit exceeded", slog.String("method", method))
+		return status.Error(codes.ResourceExhausted, "too many requests in progress, retry later")
+	}
+	defer release()
+
+	return call()
+}
+
+func limitUnary(log *slog.Logger, limits map[string]*ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	log = log.With(slog.String("component", "grpc-server/ratelimit"))
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		err = limitCall(ctx, log.With(tracing.LogAttr(ctx)), limits, info.FullMethod, func() error {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+func limitStream(log *slog.Logger, limits map[string]*ratelimit.Limiter) grpc.StreamServerInterceptor {
+	log = log.With(slog.String("component", "grpc-server/ratelimit"))
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return limitCall(ss.Context(), log.With(tracing.LogAttr(ss.Context())), limits, info.FullMethod, func() error {
+			return handler(srv, ss)
+		})
+	}
+}
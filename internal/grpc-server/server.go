@@ -0,0 +1,194 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	subscriptionsv1 "gotest_23.07.25/api/subscriptions/v1"
+	"gotest_23.07.25/internal/http-server/handlers"
+	"gotest_23.07.25/internal/http-server/middlewares/auth"
+	"gotest_23.07.25/internal/http-server/middlewares/ratelimit"
+	"gotest_23.07.25/internal/metrics"
+)
+
+// Storage - операции хранилища, которые использует gRPC API; те же, что у хендлеров HTTP API.
+type Storage interface {
+	handlers.Create
+	handlers.Read
+	handlers.Update
+	handlers.Delete
+	handlers.Export
+	handlers.RangePrice
+}
+
+// Options - настройки gRPC-сервера.
+type Options struct {
+	// Auth - способы аутентификации; без включенных способов все методы доступны без учетных данных.
+	Auth auth.Options
+	// Tracing - создавать серверный спан на каждый вызов.
+	Tracing bool
+	// Reflection - регистрировать сервис reflection.
+	Reflection bool
+	// Limits - лимиты групп методов, общие с HTTP API.
+	Limits Limits
+	// Metrics - если не nil, вызовы учитываются в метриках gRPC.
+	Metrics *metrics.Metrics
+}
+
+// Limits - лимиты групп методов SubscriptionService; nil - группа не ограничивается.
+type Limits struct {
+	// Auth - лимит по IP для всех методов до проверки учетных данных.
+	Auth *ratelimit.Limiter
+	// Read - GetSubscription и ListSubscriptions.
+	Read *ratelimit.Limiter
+	// Write - CreateSubscription, UpdateSubscription и DeleteSubscription.
+	Write *ratelimit.Limiter
+	// Reports - GetRangePrice.
+	Reports *ratelimit.Limiter
+}
+
+// authByMethod возвращает лимит Auth для всех методов SubscriptionService.
+func (l Limits) authByMethod() map[string]*ratelimit.Limiter {
+	limits := make(map[string]*ratelimit.Limiter)
+	if l.Auth == nil {
+		return limits
+	}
+	for _, m := range subscriptionsv1.SubscriptionService_ServiceDesc.Methods {
+		limits["/"+subscriptionsv1.SubscriptionService_ServiceDesc.ServiceName+"/"+m.MethodName] = l.Auth
+	}
+	for _, s := range subscriptionsv1.SubscriptionService_ServiceDesc.Streams {
+		limits["/"+subscriptionsv1.SubscriptionService_ServiceDesc.ServiceName+"/"+s.StreamName] = l.Auth
+	}
+	return limits
+}
+
+// byMethod возвращает лимиты групп по полным именам методов.
+func (l Limits) byMethod() map[string]*ratelimit.Limiter {
+	limits := make(map[string]*ratelimit.Limiter)
+	add := func(limiter *ratelimit.Limiter, methods ...string) {
+		if limiter == nil {
+			return
+		}
+		for _, m := range methods {
+			limits[m] = limiter
+		}
+	}
+
+	add(l.Read,
+		subscriptionsv1.SubscriptionService_GetSubscription_FullMethodName,
+		subscriptionsv1.SubscriptionService_ListSubscriptions_FullMethodName,
+	)
+	add(l.Write,
+		subscriptionsv1.SubscriptionService_CreateSubscription_FullMethodName,
+		subscriptionsv1.SubscriptionService_UpdateSubscription_FullMethodName,
+		subscriptionsv1.SubscriptionService_DeleteSubscription_FullMethodName,
+	)
+	add(l.Reports, subscriptionsv1.SubscriptionService_GetRangePrice_FullMethodName)
+
+	return limits
+}
+
+// Server - gRPC-сервер с SubscriptionService и стандартным сервисом проверки здоровья grpc.health.v1.
+type Server struct {
+	log    *slog.Logger
+	srv    *grpc.Server
+	health *health.Server
+}
+
+// New создает gRPC-сервер, работающий с хранилищем storage.
+// Каждый вызов логируется, паника в обработчике возвращается клиенту как INTERNAL.
+// Вызовы SubscriptionService подчиняются тем же лимитам групп, что и маршруты HTTP API.
+func New(log *slog.Logger, storage Storage, opts Options) *Server {
+	log = log.With(
+		slog.String("component", "grpc-server"),
+	)
+
+	if !opts.Auth.Enabled() {
+		log.Warn("authentication is disabled, all gRPC methods are public")
+	}
+
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
+	)
+	if opts.Tracing {
+		unary = append(unary, traceUnary())
+		stream = append(stream, traceStream())
+	}
+	if opts.Metrics != nil {
+		unary = append(unary, metricsUnary(opts.Metrics))
+		stream = append(stream, metricsStream(opts.Metrics))
+	}
+	unary = append(unary, logUnary(log), recoverUnary(log))
+	stream = append(stream, logStream(log), recoverStream(log))
+	if limits := opts.Limits.authByMethod(); len(limits) > 0 {
+		unary = append(unary, limitUnary(log, limits))
+		stream = append(stream, limitStream(log, limits))
+	}
+	if opts.Auth.Enabled() {
+		unary = append(unary, authUnary(log, opts.Auth))
+		stream = append(stream, authStream(log, opts.Auth))
+	}
+	if limits := opts.Limits.byMethod(); len(limits) > 0 {
+		unary = append(unary, limitUnary(log, limits))
+		stream = append(stream, limitStream(log, limits))
+	}
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+
+	subscriptionsv1.RegisterSubscriptionServiceServer(srv, &subscriptionService{log: log, storage: storage})
+
+	h := health.NewServer()
+	healthpb.RegisterHealthServer(srv, h)
+
+	if opts.Reflection {
+		reflection.Register(srv)
+	}
+
+	return &Server{log: log, srv: srv, health: h}
+}
+
+// Serve принимает соединения на lis до вызова Shutdown.
+func (s *Server) Serve(lis net.Listener) error {
+	const op = "grpc-server.Serve"
+
+	if err := s.srv.Serve(lis); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Drain переводит grpc.health.v1 в NOT_SERVING, чтобы клиенты перестали отправлять новые вызовы.
+func (s *Server) Drain() {
+	s.health.Shutdown()
+}
+
+// Shutdown перестает принимать вызовы и ждет завершения текущих. Если ctx завершается раньше,
+// оставшиеся вызовы прерываются, а Shutdown возвращает ошибку ctx.
+func (s *Server) Shutdown(ctx context.Context) error {
+	const op = "grpc-server.Shutdown"
+
+	stopped := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		<-stopped
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
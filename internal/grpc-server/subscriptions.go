@@ -0,0 +1,324 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	subscriptionsv1 "gotest_23.07.25/api/subscriptions/v1"
+	"gotest_23.07.25/internal/http-server/handlers"
+	"gotest_23.07.25/internal/http-server/middlewares/auth"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// subscriptionService реализует SubscriptionService поверх того же хранилища и тех же проверок, что и HTTP API.
+type subscriptionService struct {
+	subscriptionsv1.UnimplementedSubscriptionServiceServer
+	log     *slog.Logger
+	storage Storage
+}
+
+func (s *subscriptionService) CreateSubscription(ctx context.Context, req *subscriptionsv1.CreateSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	const op = "grpc-server.CreateSubscription"
+
+	log := s.log.With(
+		slog.String("op", op),
+		tracing.LogAttr(ctx),
+	)
+
+	rb, errs := subscriptionFields(req.GetSubscription())
+	errs = append(errs, validation.Subscription(rb)...)
+	if errs != nil {
+		return nil, validationError(log, errs)
+	}
+
+	if user, ok := restrictedUser(ctx); ok && !sameUser(rb.UserId, user) {
+		return nil, forbidden(log, errForeignSubscription)
+	}
+
+	id, err := s.storage.Create(ctx, rb)
+	if err != nil {
+		return nil, storageError(ctx, log, err, "Failed to create record")
+	}
+	rb.ID = id
+
+	log.Info("New record created successfully", slog.Any("record", rb))
+	return toProto(rb), nil
+}
+
+func (s *subscriptionService) GetSubscription(ctx context.Context, req *subscriptionsv1.GetSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	const op = "grpc-server.GetSubscription"
+
+	log := s.log.With(
+		slog.String("op", op),
+		tracing.LogAttr(ctx),
+	)
+
+	key, err := subscriptionKey(req.GetKey())
+	if err != nil {
+		return nil, invalidArgument(log, err.Error())
+	}
+	log = log.With(keyAttrs(key)...)
+
+	user, restricted := restrictedUser(ctx)
+	if restricted && key.ID == 0 && !sameUser(key.UserID, user) {
+		return nil, forbidden(log, errForeignSubscription)
+	}
+
+	rb, err := s.storage.Read(ctx, key)
+	if err != nil {
+		return nil, storageError(ctx, log, err, "Failed to read record")
+	}
+
+	if restricted && !sameUser(rb.UserId, user) {
+		return nil, forbidden(log, errForeignSubscription)
+	}
+
+	log.Info("Record read successfully", slog.Any("record", rb))
+	return toProto(*rb), nil
+}
+
+func (s *subscriptionService) UpdateSubscription(ctx context.Context, req *subscriptionsv1.UpdateSubscriptionRequest) (*subscriptionsv1.UpdateSubscriptionResponse, error) {
+	const op = "grpc-server.UpdateSubscription"
+
+	log := s.log.With(
+		slog.String("op", op),
+		tracing.LogAttr(ctx),
+	)
+
+	key, err := subscriptionKey(req.GetKey())
+	if err != nil {
+		return nil, invalidArgument(log, err.Error())
+	}
+	log = log.With(keyAttrs(key)...)
+
+	if req.GetVersion() < 0 {
+		return nil, invalidArgument(log, "version must not be negative")
+	}
+
+	if err := s.authorizeKey(ctx, log, key); err != nil {
+		return nil, err
+	}
+
+	price, errs := priceValue("price", req.GetPrice())
+	rb := postgre.RequestUpdateFields{
+		Price:     price,
+		StartDate: timeValue(req.GetStartDate()),
+		EndDate:   timePtr(req.GetEndDate()),
+	}
+	errs = append(errs, validation.Update(rb)...)
+	if errs != nil {
+		return nil, validationError(log, errs)
+	}
+
+	version, err := s.storage.Update(ctx, key, rb, req.GetVersion())
+	if err != nil {
+		return nil, storageError(ctx, log, err, "Failed to update record")
+	}
+
+	log.Info("Record updated successfully", slog.Any("record", rb), slog.Int64("version", version))
+	return &subscriptionsv1.UpdateSubscriptionResponse{Version: version}, nil
+}
+
+func (s *subscriptionService) DeleteSubscription(ctx context.Context, req *subscriptionsv1.DeleteSubscriptionRequest) (*subscriptionsv1.DeleteSubscriptionResponse, error) {
+	const op = "grpc-server.DeleteSubscription"
+
+	log := s.log.With(
+		slog.String("op", op),
+		tracing.LogAttr(ctx),
+	)
+
+	key, err := subscriptionKey(req.GetKey())
+	if err != nil {
+		return nil, invalidArgument(log, err.Error())
+	}
+	log = log.With(keyAttrs(key)...)
+
+	if req.GetVersion() < 0 {
+		return nil, invalidArgument(log, "version must not be negative")
+	}
+
+	if err := s.authorizeKey(ctx, log, key); err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.Delete(ctx, key, req.GetVersion()); err != nil {
+		return nil, storageError(ctx, log, err, "Failed to delete record")
+	}
+
+	log.Info("Record deleted successfully")
+	return &subscriptionsv1.DeleteSubscriptionResponse{}, nil
+}
+
+// ListSubscriptions отправляет подписки по мере чтения из хранилища, без загрузки всего списка в память.
+func (s *subscriptionService) ListSubscriptions(req *subscriptionsv1.ListSubscriptionsRequest, stream grpc.ServerStreamingServer[subscriptionsv1.Subscription]) error {
+	const op = "grpc-server.ListSubscriptions"
+
+	ctx := stream.Context()
+	log := s.log.With(
+		slog.String("op", op),
+		tracing.LogAttr(ctx),
+	)
+
+	params, errs := listParams(req)
+	if errs != nil {
+		return validationError(log, errs)
+	}
+
+	if err := scopeUserFilter(ctx, log, &params.UserID); err != nil {
+		return err
+	}
+
+	var (
+		count   int
+		sendErr error
+	)
+	err := s.storage.Export(ctx, params, func(rb postgre.RequestFields) error {
+		if sendErr = stream.Send(toProto(rb)); sendErr != nil {
+			return sendErr
+		}
+		count++
+		return nil
+	})
+	if sendErr != nil {
+		log.Info("Stream interrupted", slog.Int("count", count), slog.String("error", sendErr.Error()))
+		return sendErr
+	}
+	if err != nil {
+		return storageError(ctx, log.With(slog.Int("count", count)), err, "Failed to list subscriptions")
+	}
+
+	log.Info("Subscriptions listed successfully", slog.Int("count", count))
+	return nil
+}
+
+func (s *subscriptionService) GetRangePrice(ctx context.Context, req *subscriptionsv1.GetRangePriceRequest) (*subscriptionsv1.GetRangePriceResponse, error) {
+	const op = "grpc-server.GetRangePrice"
+
+	log := s.log.With(
+		slog.String("op", op),
+		tracing.LogAttr(ctx),
+	)
+
+	rb := handlers.RangeRequestBody{
+		StartDate:   timeValue(req.GetStartDate()),
+		EndDate:     timeValue(req.GetEndDate()),
+		ServiceName: req.GetServiceName(),
+		UserID:      req.GetUserId(),
+	}
+	if errs := rb.Validate(); errs != nil {
+		return nil, validationError(log, errs)
+	}
+
+	if err := scopeUserFilter(ctx, log, &rb.UserID); err != nil {
+		return nil, err
+	}
+
+	price, err := s.storage.RangePrice(ctx, rb.StartDate, rb.EndDate, rb.ServiceName, rb.UserID)
+	if err != nil {
+		return nil, storageError(ctx, log, err, "Failed to get range price")
+	}
+
+	log.Info("Get range price successfully", slog.Uint64("price", price))
+	return &subscriptionsv1.GetRangePriceResponse{Price: price}, nil
+}
+
+// listParams возвращает фильтры и сортировку списка с теми же ограничениями, что у query-параметров HTTP API.
+func listParams(req *subscriptionsv1.ListSubscriptionsRequest) (postgre.ListParams, validation.Errors) {
+	params := postgre.ListParams{
+		ServiceName: req.GetServiceName(),
+		UserID:      req.GetUserId(),
+		ActiveOn:    timePtr(req.GetActiveOn()),
+		Sort:        req.GetSort(),
+		Desc:        req.GetDesc(),
+	}
+
+	errs := validation.UUID("user_id", params.UserID, false)
+
+	if req.PriceMin != nil {
+		price, priceErrs := priceValue("price_min", req.GetPriceMin())
+		params.PriceMin, errs = &price, append(errs, priceErrs...)
+	}
+	if req.PriceMax != nil {
+		price, priceErrs := priceValue("price_max", req.GetPriceMax())
+		params.PriceMax, errs = &price, append(errs, priceErrs...)
+	}
+
+	switch params.Sort {
+	case "", postgre.SortByPrice, postgre.SortByStartDate, postgre.SortByServiceName:
+	default:
+		errs = append(errs, validation.FieldError{
+			Field:   "sort",
+			Code:    validation.CodeInvalidValue,
+			Message: "sort must be one of price, start_date, service_name",
+		})
+	}
+
+	return params, errs
+}
+
+// restrictedUser возвращает пользователя, подписками которого ограничен клиент.
+// Ключи API и JWT с ролью администратора не ограничены.
+func restrictedUser(ctx context.Context) (string, bool) {
+	id, ok := auth.FromContext(ctx)
+	if !ok || id.UserID == "" {
+		return "", false
+	}
+	return id.UserID, true
+}
+
+// sameUser сравнивает UUID пользователей без учета регистра.
+func sameUser(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// scopeUserFilter ограничивает фильтр user_id пользователем клиента: пустой фильтр заменяется на него,
+// фильтр по другому пользователю запрещен.
+func scopeUserFilter(ctx context.Context, log *slog.Logger, userID *string) error {
+	user, ok := restrictedUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	if *userID != "" && !sameUser(*userID, user) {
+		return forbidden(log, "user_id filter must match the authenticated user")
+	}
+
+	*userID = user
+	return nil
+}
+
+// authorizeKey проверяет, что подписка с ключом key принадлежит пользователю клиента.
+// Для ключа по id владелец читается из хранилища.
+func (s *subscriptionService) authorizeKey(ctx context.Context, log *slog.Logger, key postgre.SubscriptionKey) error {
+	user, ok := restrictedUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	owner := key.UserID
+	if key.ID != 0 {
+		rb, err := s.storage.Read(ctx, key)
+		if err != nil {
+			return storageError(ctx, log, err, "Failed to read record owner")
+		}
+		owner = rb.UserId
+	}
+
+	if !sameUser(owner, user) {
+		return forbidden(log, errForeignSubscription)
+	}
+
+	return nil
+}
+
+// keyAttrs возвращает атрибуты лога для ключа подписки.
+func keyAttrs(key postgre.SubscriptionKey) []any {
+	if key.ID != 0 {
+		return []any{slog.Int64("id", key.ID)}
+	}
+	return []any{slog.String("service_name", key.ServiceName), slog.String("user_id", key.UserID)}
+}
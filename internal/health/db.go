@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// names of database checks:
+const (
+	CheckPool       = "db_pool"
+	CheckPing       = "db_ping"
+	CheckMigrations = "db_migrations"
+)
+
+// migrationsQuery читает версию схемы из таблицы golang-migrate; запрос одинаков для postgres и SQLite.
+const migrationsQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+// DBOptions - пороги проверок готовности БД.
+type DBOptions struct {
+	// MaxPingLatency - наибольшая допустимая задержка ping БД.
+	MaxPingLatency time.Duration
+	// MaxPoolSaturation - наибольшая допустимая доля занятых соединений пула, от 0 до 1.
+	MaxPoolSaturation float64
+	// MigrationVersion - версия последней миграции, встроенной в бинарник.
+	MigrationVersion uint
+}
+
+// AddDB добавляет проверки БД: занятость пула соединений, задержку ping и совпадение
+// примененной версии миграций с встроенной. Пул проверяется первым, пока ping не занял соединение.
+func (h *Health) AddDB(db *sql.DB, opts DBOptions) {
+	h.Add(CheckPool, poolCheck(db, opts.MaxPoolSaturation))
+	h.Add(CheckPing, pingCheck(db, opts.MaxPingLatency))
+	h.Add(CheckMigrations, migrationsCheck(db, opts.MigrationVersion))
+}
+
+// poolCheck проверяет, что пул соединений не занят больше чем на maxSaturation.
+// Пул без ограничения числа соединений не насыщается.
+func poolCheck(db *sql.DB, maxSaturation float64) CheckFunc {
+	return func(ctx context.Context) Check {
+		stats := db.Stats()
+
+		var saturation float64
+		if stats.MaxOpenConnections > 0 {
+			saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		}
+
+		details := map[string]any{
+			"in_use":     stats.InUse,
+			"max_open":   stats.MaxOpenConnections,
+			"saturation": saturation,
+			"wait_count": stats.WaitCount,
+		}
+		if saturation >= maxSaturation {
+			return fail(fmt.Sprintf("connection pool saturation %.2f reached limit %.2f", saturation, maxSaturation), details)
+		}
+		return ok(details)
+	}
+}
+
+// pingCheck проверяет, что БД отвечает на ping не дольше maxLatency.
+func pingCheck(db *sql.DB, maxLatency time.Duration) CheckFunc {
+	return func(ctx context.Context) Check {
+		t1 := time.Now()
+		err := db.PingContext(ctx)
+		latency := time.Since(t1)
+
+		details := map[string]any{"latency_ms": latency.Milliseconds()}
+		if err != nil {
+			return fail("ping failed: "+err.Error(), details)
+		}
+		if latency > maxLatency {
+			return fail(fmt.Sprintf("ping latency %s exceeds %s", latency, maxLatency), details)
+		}
+		return ok(details)
+	}
+}
+
+// migrationsCheck проверяет, что к БД применены все встроенные миграции и последняя из них не прервана.
+func migrationsCheck(db *sql.DB, expected uint) CheckFunc {
+	return func(ctx context.Context) Check {
+		var (
+			version int64
+			dirty   bool
+		)
+		err := db.QueryRowContext(ctx, migrationsQuery).Scan(&version, &dirty)
+		if err != nil {
+			return fail("failed to read migration version: "+err.Error(), map[string]any{"expected": expected})
+		}
+
+		details := map[string]any{
+			"version":  version,
+			"expected": expected,
+			"dirty":    dirty,
+		}
+		switch {
+		case dirty:
+			return fail(fmt.Sprintf("migration %d is dirty", version), details)
+		case version != int64(expected):
+			return fail(fmt.Sprintf("applied migration %d does not match embedded %d", version, expected), details)
+		}
+		return ok(details)
+	}
+}
@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statuses:
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Check - результат одной проверки готовности. Details - измеренные значения для диагностики.
+type Check struct {
+	Status  string         `json:"status" example:"ok"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// CheckFunc выполняет одну проверку готовности.
+type CheckFunc func(ctx context.Context) Check
+
+// Report - результат проверки готовности сервиса.
+type Report struct {
+	Status string           `json:"status" example:"ok"`
+	Checks map[string]Check `json:"checks,omitempty"`
+}
+
+// Ready возвращает true, если сервис готов принимать запросы.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name string
+	fn   CheckFunc
+}
+
+// Health собирает проверки готовности и хранит признак остановки сервиса.
+type Health struct {
+	timeout  time.Duration
+	draining atomic.Bool
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+// New возвращает Health без проверок. timeout ограничивает время всех проверок одного запроса.
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// Add добавляет проверку готовности с именем name.
+func (h *Health) Add(name string, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, namedCheck{name: name, fn: fn})
+}
+
+// Drain помечает сервис останавливающимся: после вызова Ready сообщает о неготовности без проверок,
+// чтобы балансировщик перестал отправлять запросы до остановки HTTP-сервера.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Ready выполняет проверки готовности по очереди, в порядке добавления. Сервис готов, если все проверки прошли.
+func (h *Health) Ready(ctx context.Context) Report {
+	if h.draining.Load() {
+		return Report{Status: StatusDraining}
+	}
+
+	h.mu.RLock()
+	checks := h.checks
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Check, len(checks))}
+	for _, c := range checks {
+		check := c.fn(ctx)
+		report.Checks[c.name] = check
+		if check.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+// ok возвращает успешную проверку с измеренными значениями details.
+func ok(details map[string]any) Check {
+	return Check{Status: StatusOK, Details: details}
+}
+
+// fail возвращает проваленную проверку с причиной msg и измеренными значениями details.
+func fail(msg string, details map[string]any) Check {
+	return Check{Status: StatusFail, Error: msg, Details: details}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/apikey"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// maxKeyNameLength - максимальная длина имени ключа API в символах.
+const maxKeyNameLength = 100
+
+type IssueKey interface {
+	CreateAPIKey(ctx context.Context, key postgre.APIKey, hash string) (*postgre.APIKey, error)
+}
+
+type ListKeys interface {
+	ListAPIKeys(ctx context.Context) ([]postgre.APIKey, error)
+}
+
+type RevokeKey interface {
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
+
+type IssueKeyRequest struct {
+	Name   string   `json:"name" example:"billing-service"`
+	Scopes []string `json:"scopes" example:"subscriptions:read,reports:read"`
+}
+
+type IssueKeyResponse struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Key     string         `json:"key" example:"subs_3fA9..."`
+	APIKey  postgre.APIKey `json:"api_key"`
+}
+
+type ListKeysResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Keys    []postgre.APIKey `json:"keys"`
+}
+
+// Validate проверяет имя ключа и области доступа.
+func (rb IssueKeyRequest) Validate() validation.Errors {
+	var errs validation.Errors
+
+	switch name := strings.TrimSpace(rb.Name); {
+	case name == "":
+		errs = append(errs, validation.FieldError{Field: "name", Code: validation.CodeRequired, Message: "name is required"})
+	case utf8.RuneCountInString(name) > maxKeyNameLength:
+		errs = append(errs, validation.FieldError{
+			Field:   "name",
+			Code:    validation.CodeTooLong,
+			Message: fmt.Sprintf("name must be at most %d characters long", maxKeyNameLength),
+		})
+	}
+
+	if len(rb.Scopes) == 0 {
+		errs = append(errs, validation.FieldError{Field: "scopes", Code: validation.CodeRequired, Message: "scopes are required"})
+	}
+	for _, scope := range rb.Scopes {
+		if !apikey.ValidScope(scope) {
+			errs = append(errs, validation.FieldError{
+				Field:   "scopes",
+				Code:    validation.CodeInvalidValue,
+				Message: fmt.Sprintf("unknown scope %q, expected one of: %s", scope, strings.Join(apikey.Scopes, ", ")),
+			})
+		}
+	}
+
+	return errs
+}
+
+// NewIssueKey возвращает хендлер, выпускающий новый ключ API
+//
+// @Summary Выпустить ключ API
+// @Description Возвращает ключ целиком только в этом ответе: в БД хранится его хеш. Области доступа: subscriptions:read, subscriptions:write, reports:read, admin.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param key body IssueKeyRequest true "Имя и области доступа ключа"
+// @Success 201 {object} IssueKeyResponse
+// @Failure 400 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/admin/keys [post]
+func NewIssueKey(log *slog.Logger, storage IssueKey) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.NewIssueKey"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
+		)
+
+		log.Info("IssueKey handler started")
+
+		var rb IssueKeyRequest
+		if errs := validation.DecodeJSON(r.Body, &rb); errs != nil {
+			writeValidationError(w, r, log, errs)
+			return
+		}
+		if errs := rb.Validate(); errs != nil {
+			writeValidationError(w, r, log, errs)
+			return
+		}
+
+		key, prefix, err := apikey.Generate()
+		if err != nil {
+			log.Error("Failed to generate api key", slog.String("error", err.Error()))
+			response.WriteError(w, r, response.Internal, "")
+			return
+		}
+
+		created, err := storage.CreateAPIKey(r.Context(), postgre.APIKey{
+			Name:   strings.TrimSpace(rb.Name),
+			Prefix: prefix,
+			Scopes: rb.Scopes,
+		}, apikey.Hash(key))
+		if err != nil {
+			writeStorageError(w, r, log, err, "Failed to create api key")
+			return
+		}
+
+		log.Info("API key issued", slog.Int64("id", created.ID), slog.String("name", created.Name), slog.Any("scopes", created.Scopes))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, IssueKeyResponse{
+			Status:  "success",
+			Message: "API key issued, store it now: it cannot be shown again",
+			Key:     key,
+			APIKey:  *created,
+		})
+	}
+}
+
+// NewListKeys возвращает хендлер, возвращающий список ключей API
+//
+// @Summary Получить список ключей API
+// @Description Возвращает все ключи, включая отозванные, без самих ключей: только их открытое начало.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} ListKeysResponse
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/admin/keys [get]
+func NewListKeys(log *slog.Logger, storage ListKeys) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.NewListKeys"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
+		)
+
+		log.Info("ListKeys handler started")
+
+		keys, err := storage.ListAPIKeys(r.Context())
+		if err != nil {
+			writeStorageError(w, r, log, err, "Failed to list api keys")
+			return
+		}
+
+		log.Info("API keys listed successfully", slog.Int("count", len(keys)))
+		render.JSON(w, r, ListKeysResponse{
+			Status:  "success",
+			Message: "API keys listed successfully",
+			Keys:    keys,
+		})
+	}
+}
+
+// NewRevokeKey возвращает хендлер, отзывающий ключ API
+//
+// @Summary Отозвать ключ API
+// @Description Отозванный ключ перестает приниматься сразу. Повторный отзыв возвращает 404.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "ID ключа"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/admin/keys/{id} [delete]
+func NewRevokeKey(log *slog.Logger, storage RevokeKey) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.NewRevokeKey"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
+		)
+
+		log.Info("RevokeKey handler started")
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			response.WriteError(w, r, response.BadRequest, errInvalidID.Error())
+			return
+		}
+
+		if err := storage.RevokeAPIKey(r.Context(), id); err != nil {
+			writeStorageError(w, r, log.With(slog.Int64("id", id)), err, "Failed to revoke api key")
+			return
+		}
+
+		log.Info("API key revoked", slog.Int64("id", id))
+		render.JSON(w, r, DeleteResponse{
+			Status:  "success",
+			Message: "API key was revoked successfully",
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// batch modes:
+const (
+	batchModeAtomic = "atomic"
+	batchModePerRow = "per_row"
+)
+
+// batch row statuses:
+const (
+	batchCreated   = "created"
+	batchDuplicate = "duplicate"
+	batchInvalid   = "invalid"
+	batchSkipped   = "skipped"
+)
+
+const (
+	maxBatchRows     = 10000
+	maxBatchBodySize = 32 << 20
+)
+
+var (
+	errEmptyBatch   = errors.New("batch is empty")
+	errBatchTooBig  = fmt.Errorf("batch is too large: at most %d rows are allowed", maxBatchRows)
+	errInvalidBatch = errors.New("invalid batch body")
+)
+
+type CreateBatch interface {
+	CreateBatch(ctx context.Context, rows []postgre.RequestFields, atomic bool) ([]postgre.BatchResult, error)
+}
+
+type BatchRowResult struct {
+	Row      int                     `json:"row" example:"1"`
+	Status   string                  `json:"status" example:"created"`
+	ID       int64                   `json:"id,omitempty" example:"1"`
+	Error    string                  `json:"error,omitempty"`
+	Errors   []validation.FieldError `json:"errors,omitempty"`
+	Existing *postgre.RequestFields  `json:"existing,omitempty"`
+}
+
+type BatchResponse struct {
+	Status     string           `json:"status"`
+	Message    string           `json:"message"`
+	Mode       string           `json:"mode" example:"atomic"`
+	Created    int              `json:"created"`
+	Duplicates int              `json:"duplicates"`
+	Invalid    int              `json:"invalid"`
+	Skipped    int              `json:"skipped"`
+	Results    []BatchRowResult `json:"results"`
+}
+
+// batchRow - разобранная строка пакета; errs - причины, по которым строка невалидна.
+type batchRow struct {
+	fields postgre.RequestFields
+	errs   validation.Errors
+}
+
+// NewCreateBatch возвращает хендлер, создающий пакет записей о подписках
+//
+// @Summary Создать пакет записей о подписках
+// @Description Принимает записи в формате JSON-массива (application/json), NDJSON (application/x-ndjson) или CSV (text/csv).
+// @Description CSV должен содержать строку заголовка с колонками service_name, price, user_id, start_date и необязательной end_date; даты в формате YYYY-MM-DD или RFC 3339.
+// @Description В режиме atomic (по умолчанию) записи создаются одной транзакцией: если хотя бы одна строка невалидна или пересекается с существующим периодом, не создается ни одна (ответ 422, такие строки получают статус skipped).
+// @Description В режиме per_row создаются все корректные строки. Для каждой строки возвращается статус created, duplicate, invalid или skipped.
+// @Tags subscriptions
+// @Accept json,text/csv,application/x-ndjson
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param mode query string false "Режим: atomic или per_row" Enums(atomic, per_row)
+// @Param subscriptions body []postgre.RequestFields true "Записи для внесения"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом и телом возвращает сохраненный ответ"
+// @Success 200 {object} BatchResponse
+// @Failure 400 {object} response.Problem
+// @Failure 415 {object} response.Problem
+// @Failure 422 {object} BatchResponse
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/subscriptions:batch [post]
+func NewCreateBatch(log *slog.Logger, storage CreateBatch) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.NewCreateBatch"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
+		)
+
+		log.Info("CreateBatch handler started")
+
+		mode := r.URL.Query().Get("mode")
+		switch mode {
+		case "":
+			mode = batchModeAtomic
+		case batchModeAtomic, batchModePerRow:
+		default:
+			log.Info("Unknown batch mode", slog.String("mode", mode))
+			response.WriteError(w, r, response.BadRequest, "unknown mode: "+mode)
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		var decode func(io.Reader) ([]batchRow, error)
+		switch mediaType {
+		case "application/json":
+			decode = decodeJSONBatch
+		case "application/x-ndjson", "application/ndjson":
+			decode = decodeNDJSONBatch
+		case "text/csv":
+			decode = decodeCSVBatch
+		default:
+			log.Info("Unsupported content type", slog.String("content_type", mediaType))
+			response.WriteError(w, r, response.UnsupportedMediaType, "content type must be application/json, application/x-ndjson or text/csv")
+			return
+		}
+
+		rows, err := decode(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+		if err == nil && len(rows) == 0 {
+			err = errEmptyBatch
+		}
+		if err != nil {
+			log.Info("Failed to decode batch", slog.String("error", err.Error()))
+			response.WriteError(w, r, response.BadRequest, err.Error())
+			return
+		}
+
+		if user, ok := restrictedUser(r); ok {
+			for i, row := range rows {
+				if row.fields.UserId != "" && !sameUser(row.fields.UserId, user) {
+					writeForbidden(w, r, log, fmt.Sprintf("row %d: %s", i+1, errForeignSubscription))
+					return
+				}
+			}
+		}
+
+		resp := BatchResponse{
+			Status:  "success",
+			Message: "Batch processed",
+			Mode:    mode,
+			Results: make([]BatchRowResult, len(rows)),
+		}
+
+		var (
+			valid   []postgre.RequestFields
+			indexes []int
+		)
+		for i, row := range rows {
+			resp.Results[i].Row = i + 1
+			if row.errs == nil {
+				row.errs = validation.Subscription(row.fields)
+			}
+			if row.errs != nil {
+				resp.Results[i].Status = batchInvalid
+				resp.Results[i].Error = row.errs.Error()
+				resp.Results[i].Errors = row.errs
+				resp.Invalid++
+				continue
+			}
+			valid = append(valid, row.fields)
+			indexes = append(indexes, i)
+		}
+
+		atomic := mode == batchModeAtomic
+
+		var results []postgre.BatchResult
+		if len(valid) > 0 && !(atomic && resp.Invalid > 0) {
+			results, err = storage.CreateBatch(r.Context(), valid, atomic)
+			if err != nil {
+				writeStorageError(w, r, log, err, "Failed to create batch")
+				return
+			}
+		}
+
+		for j, i := range indexes {
+			res := &resp.Results[i]
+			switch {
+			case results == nil || (results[j].ID == 0 && results[j].Err == nil):
+				res.Status = batchSkipped
+				resp.Skipped++
+			case results[j].Err != nil && !errors.Is(results[j].Err, postgre.ErrConflict):
+				res.Status = batchInvalid
+				res.Error, _ = postgre.ErrorReason(results[j].Err)
+				resp.Invalid++
+			case results[j].Err != nil:
+				res.Status = batchDuplicate
+				res.Error = results[j].Err.Error()
+				var overlap *postgre.OverlapError
+				if errors.As(results[j].Err, &overlap) {
+					res.Existing = &overlap.Existing
+				}
+				resp.Duplicates++
+			default:
+				res.Status = batchCreated
+				res.ID = results[j].ID
+				resp.Created++
+			}
+		}
+
+		status := http.StatusOK
+		if atomic && resp.Created < len(rows) {
+			status = http.StatusUnprocessableEntity
+			resp.Status = "error"
+			resp.Message = "Batch rolled back"
+		}
+
+		log.Info("Batch processed",
+			slog.String("mode", mode),
+			slog.Int("created", resp.Created),
+			slog.Int("duplicates", resp.Duplicates),
+			slog.Int("invalid", resp.Invalid),
+			slog.Int("skipped", resp.Skipped),
+		)
+		render.Status(r, status)
+		render.JSON(w, r, resp)
+	}
+}
+
+// decodeJSONBatch разбирает JSON-массив записей.
+func decodeJSONBatch(body io.Reader) ([]batchRow, error) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&items); err != nil {
+		return nil, errInvalidBatch
+	}
+	if len(items) > maxBatchRows {
+		return nil, errBatchTooBig
+	}
+
+	rows := make([]batchRow, len(items))
+	for i, item := range items {
+		rows[i] = decodeJSONRow(item)
+	}
+	return rows, nil
+}
+
+// decodeNDJSONBatch разбирает записи, по одной JSON-записи на строку. Пустые строки пропускаются.
+func decodeNDJSONBatch(body io.Reader) ([]batchRow, error) {
+	var rows []batchRow
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(rows) == maxBatchRows {
+			return nil, errBatchTooBig
+		}
+		rows = append(rows, decodeJSONRow(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errInvalidBatch
+	}
+
+	return rows, nil
+}
+
+// decodeJSONRow строго разбирает одну запись. id и version (например, из выгрузки) игнорируются.
+func decodeJSONRow(data []byte) batchRow {
+	var row batchRow
+	row.errs = validation.DecodeJSON(bytes.NewReader(data), &row.fields)
+	row.fields.ID, row.fields.Version = 0, 0
+	return row
+}
+
+// decodeCSVBatch разбирает CSV с заголовком. Колонки сопоставляются по именам из заголовка,
+// колонки id и version пропускаются, чтобы выгрузку /export можно было загрузить обратно.
+func decodeCSVBatch(body io.Reader) ([]batchRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errInvalidBatch
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		switch name {
+		case "service_name", "price", "user_id", "start_date", "end_date":
+			columns[name] = i
+		case "id", "version":
+			// Колонки выгрузки /export игнорируются, как и поля id и version в JSON.
+		default:
+			return nil, fmt.Errorf("unknown csv column: %s", name)
+		}
+	}
+	for _, name := range []string{"service_name", "price", "user_id", "start_date"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing csv column: %s", name)
+		}
+	}
+
+	var rows []batchRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if len(rows) == maxBatchRows {
+			return nil, errBatchTooBig
+		}
+		if err != nil {
+			if errors.Is(err, csv.ErrFieldCount) {
+				rows = append(rows, batchRow{errs: validation.Errors{{
+					Code:    validation.CodeInvalidValue,
+					Message: "wrong number of fields",
+				}}})
+				continue
+			}
+			return nil, fmt.Errorf("%w: %w", errInvalidBatch, err)
+		}
+		rows = append(rows, decodeCSVRow(record, columns))
+	}
+
+	return rows, nil
+}
+
+func decodeCSVRow(record []string, columns map[string]int) batchRow {
+	var row batchRow
+
+	row.fields.ServiceName = record[columns["service_name"]]
+	row.fields.UserId = record[columns["user_id"]]
+
+	price, err := strconv.ParseUint(record[columns["price"]], 10, 16)
+	if err != nil {
+		row.errs = append(row.errs, invalidType("price", "whole number in range"))
+	}
+	row.fields.Price = uint16(price)
+
+	if row.fields.StartDate, err = parseDate(record[columns["start_date"]]); err != nil {
+		row.errs = append(row.errs, invalidType("start_date", "date"))
+	}
+
+	if i, ok := columns["end_date"]; ok && record[i] != "" {
+		endDate, err := parseDate(record[i])
+		if err != nil {
+			row.errs = append(row.errs, invalidType("end_date", "date"))
+		} else {
+			row.fields.EndDate = &endDate
+		}
+	}
+
+	return row
+}
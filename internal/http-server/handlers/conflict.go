@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// writeConflict отвечает 409, если err - пересечение периодов подписки, и сообщает, был ли отправлен ответ.
+// В ответ попадает период, с которым пересекается запрос, если хранилище его вернуло.
+func writeConflict(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) bool {
+	if !errors.Is(err, postgre.ErrSubscriptionExists) {
+		return false
+	}
+
+	log.Info("Subscription period overlaps", slog.String("error", err.Error()))
+
+	var overlap *postgre.OverlapError
+	if errors.As(err, &overlap) {
+		response.WriteProblem(w, r, response.ConflictProblem(overlap.Error(), &overlap.Existing))
+		return true
+	}
+
+	response.WriteProblem(w, r, response.ConflictProblem(err.Error(), nil))
+	return true
+}
@@ -1,73 +1,82 @@
 package handlers
 
 import (
-	"errors"
+	"context"
 	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/validation"
 	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
 )
 
 type Create interface {
-	Create(rb postgre.RequestFields) (string, error)
-}
-
-type ErrorResponse struct {
-	Message string `json:"message" example:"invalid request body"`
+	Create(ctx context.Context, rb postgre.RequestFields) (int64, error)
 }
 
 // NewCreate возвращает хендлер, создающий новую запись в таблице
 //
 // @Summary Создать новую запись о подписке
-// @Description Возвращает поля записи
+// @Description Возвращает поля записи вместе с ее id. У одной пары (service_name, user_id) может быть несколько периодов подписки,
+// @Description но они не должны пересекаться: при пересечении возвращается 409 с описанием существующего периода в fields.
+// @Description Некорректные поля, неизвестные поля тела и заданные в теле id или version (код read_only) возвращаются ответом 400 со списком ошибок в errors.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Param subscription body postgre.RequestFields true "Данные для внесения"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом и телом возвращает сохраненный ответ"
 // @Success 200 {object} response.Response
-// @Failure 400 {object} response.Response
-// @Failure 409 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Failure 422 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
 // @Router /api/v1/subscriptions [post]
 func NewCreate(log *slog.Logger, storage Create) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "http-server.handlers.NewCreate"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
 		)
 
 		log.Info("Create handler started")
 
 		var rb postgre.RequestFields
 
-		if err := render.DecodeJSON(r.Body, &rb); err != nil {
-			log.Error("Failed to decode request body", slog.String("error", err.Error()))
-			render.JSON(w, r, response.Error("invalid request body"))
-			w.WriteHeader(http.StatusBadRequest)
+		if errs := validation.DecodeJSON(r.Body, &rb); errs != nil {
+			writeValidationError(w, r, log, errs)
 			return
 		}
 		log.Debug("Decoded request body", slog.Any("request_body", rb))
 
-		_, err := storage.Create(rb)
+		if errs := validation.Subscription(rb); errs != nil {
+			writeValidationError(w, r, log, errs)
+			return
+		}
+
+		if user, ok := restrictedUser(r); ok && !sameUser(rb.UserId, user) {
+			writeForbidden(w, r, log, errForeignSubscription)
+			return
+		}
+
+		id, err := storage.Create(r.Context(), rb)
 		if err != nil {
-			if errors.Is(err, postgre.ErrSubscriptionExists) {
-				log.Error("Record already exists")
-				w.WriteHeader(http.StatusConflict)
-				render.JSON(w, r, response.Error("Record already exists"))
-				return
-			}
-			log.Error("Failed to create record", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, response.Error("internal error"))
+			writeStorageError(w, r, log, err, "Failed to create record")
 			return
 		}
+		rb.ID = id
 		log.Info("New record created successfully", slog.Any("record", rb))
-		w.WriteHeader(http.StatusOK)
 		render.JSON(w, r, response.OK("New record created", &rb))
 	}
 }
@@ -1,19 +1,21 @@
 package handlers
 
 import (
-	"database/sql"
-	"errors"
+	"context"
 	"log/slog"
 	"net/http"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
 )
 
 type Delete interface {
-	Delete(serviceName, userID string) error
+	// Read нужен для проверки владельца подписки, к которой обращаются по id.
+	Read
+	Delete(ctx context.Context, key postgre.SubscriptionKey, version int64) error
 }
 
 type DeleteResponse struct {
@@ -24,54 +26,86 @@ type DeleteResponse struct {
 // NewDelete возвращает хендлер, удаляющий запись из таблицы
 //
 // @Summary Удалить запись о подписке
-// @Description Удаляет запись по service_name и user_id
+// @Description Удаляет запись по service_name и user_id. Если периодов подписки несколько, используется последний по start_date
 // @Tags subscriptions
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Param service_name path string true "Имя сервися"
 // @Param user_id path string true "UUID пользователя"
+// @Param If-Match header string false "ETag из ответа GET; запрос выполняется только для этой версии записи"
 // @Success 200 {object} response.Response
-// @Failure 400 {object} response.Response
-// @Failure 404 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 412 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
 // @Router /api/v1/subscriptions/{service_name}/{user_id} [delete]
 func NewDelete(log *slog.Logger, storage Delete) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "http-server.handlers.NewDelete"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
 		)
 
 		log.Info("Delete handler started")
 
-		serviceName := chi.URLParam(r, "service_name")
-		userID := chi.URLParam(r, "user_id")
-		if serviceName == "" || userID == "" {
-			log.Info("url param is empty")
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("url param is empty"))
+		key, err := subscriptionKey(r)
+		if err != nil {
+			log.Info("Invalid url params", slog.String("error", err.Error()))
+			response.WriteError(w, r, response.BadRequest, err.Error())
 			return
 		}
 
-		if err := storage.Delete(serviceName, userID); err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				log.Warn("record not found: %s, %s", serviceName, userID)
-				w.WriteHeader(http.StatusNotFound)
-				render.JSON(w, r, response.Error("record not found"))
-				return
-			}
-			log.Error("Failed to delete record", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, response.Error("internal error"))
+		if !authorizeKey(w, r, log, storage, key) {
 			return
 		}
 
-		log.Info("Record deleted successfully", slog.String("service_name", serviceName), slog.String("user_id", userID))
-		w.WriteHeader(http.StatusOK)
+		version, err := ifMatchVersion(r)
+		if err != nil {
+			writePreconditionFailed(w, r, log, err)
+			return
+		}
+
+		if err := storage.Delete(r.Context(), key, version); err != nil {
+			writeStorageError(w, r, log.With(keyAttrs(key)...), err, "Failed to delete record")
+			return
+		}
+
+		log.Info("Record deleted successfully", keyAttrs(key)...)
 		render.JSON(w, r, DeleteResponse{
 			Status:  "success",
 			Message: "record was deleted successfully",
 		})
 	}
 }
+
+// NewDeleteByID возвращает хендлер, удаляющий запись о подписке по ее id
+//
+// @Summary Удалить запись о подписке по id
+// @Description Удаляет запись по id
+// @Tags subscriptions
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path int true "ID подписки"
+// @Param If-Match header string false "ETag из ответа GET; запрос выполняется только для этой версии записи"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 412 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/subscriptions/{id} [delete]
+func NewDeleteByID(log *slog.Logger, storage Delete) http.HandlerFunc {
+	return NewDelete(log, storage)
+}
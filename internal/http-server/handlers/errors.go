@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// unavailableRetryAfter - через сколько секунд клиенту стоит повторить запрос, если хранилище недоступно.
+const unavailableRetryAfter = "5"
+
+// writeStorageError отвечает клиенту по категории ошибки хранилища: 404, 409, 412, 400 или 503.
+// Операции, прерванные клиентом, получают 499, прерванные по таймауту или остановкой сервера - 503.
+// Ошибки без категории логируются с сообщением msg и возвращаются как 500.
+func writeStorageError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error, msg string) {
+	if writeCanceled(w, r, log, err) || writePreconditionFailed(w, r, log, err) || writeConflict(w, r, log, err) {
+		return
+	}
+
+	reason, _ := postgre.ErrorReason(err)
+
+	switch {
+	case errors.Is(err, postgre.ErrNotFound):
+		log.Warn("Record not found", slog.String("error", err.Error()))
+		response.WriteError(w, r, response.NotFound, reason)
+	case errors.Is(err, postgre.ErrInvalidPeriod):
+		writeValidationError(w, r, log, validation.Errors{{
+			Field:   "end_date",
+			Code:    validation.CodeEndBeforeStart,
+			Message: "end_date cannot be before start_date",
+		}})
+	case errors.Is(err, postgre.ErrInvalidInput):
+		log.Info("Invalid input", slog.String("error", err.Error()))
+		response.WriteError(w, r, response.BadRequest, reason)
+	case errors.Is(err, postgre.ErrConflict):
+		log.Info("Conflict", slog.String("error", err.Error()))
+		response.WriteError(w, r, response.Conflict, reason)
+	case errors.Is(err, postgre.ErrUnavailable):
+		log.Error("Storage unavailable", slog.String("error", err.Error()))
+		w.Header().Set("Retry-After", unavailableRetryAfter)
+		response.WriteError(w, r, response.Unavailable, reason)
+	default:
+		log.Error(msg, slog.String("error", err.Error()))
+		response.WriteError(w, r, response.Internal, "")
+	}
+}
+
+// writeCanceled отвечает, если операция хранилища прервана отменой контекста, и сообщает, был ли отправлен ответ.
+// Сервер при остановке отменяет контексты запросов с причиной http.ErrServerClosed.
+func writeCanceled(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) bool {
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	switch {
+	case errors.Is(context.Cause(r.Context()), http.ErrServerClosed):
+		log.Warn("Request canceled by server shutdown", slog.String("error", err.Error()))
+		w.Header().Set("Retry-After", unavailableRetryAfter)
+		response.WriteError(w, r, response.Unavailable, "server is shutting down")
+	case r.Context().Err() != nil:
+		log.Info("Request canceled by client", slog.String("error", err.Error()))
+		response.WriteError(w, r, response.ClientClosedRequest, "")
+	default:
+		log.Error("Storage operation timed out", slog.String("error", err.Error()))
+		w.Header().Set("Retry-After", unavailableRetryAfter)
+		response.WriteError(w, r, response.Unavailable, "storage operation timed out")
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/postgre"
+)
+
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// etag возвращает значение заголовка ETag для версии записи.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion возвращает версию записи из заголовка If-Match: 0, если заголовка нет или он равен "*".
+// Слабые и неразборчивые ETag не могут совпасть с версией записи, для них возвращается errInvalidIfMatch.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+
+	version, err := strconv.ParseInt(v[1:len(v)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errInvalidIfMatch
+	}
+
+	return version, nil
+}
+
+// writePreconditionFailed отвечает 412, если err - несовпадение версии записи или неверный If-Match,
+// и сообщает, был ли отправлен ответ.
+func writePreconditionFailed(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) bool {
+	if !errors.Is(err, postgre.ErrVersionMismatch) && !errors.Is(err, errInvalidIfMatch) {
+		return false
+	}
+
+	log.Info("Precondition failed", slog.String("if_match", r.Header.Get("If-Match")), slog.String("error", err.Error()))
+	response.WriteError(w, r, response.PreconditionFailed, err.Error())
+	return true
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// export formats:
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+)
+
+// exportFlushRows - через сколько строк выгрузка отправляется клиенту.
+const exportFlushRows = 500
+
+// exportColumns - колонки CSV-выгрузки.
+var exportColumns = []string{"id", "service_name", "price", "user_id", "start_date", "end_date", "version"}
+
+type Export interface {
+	Export(ctx context.Context, params postgre.ListParams, fn func(postgre.RequestFields) error) error
+}
+
+// exportEncoder пишет строки выгрузки в буфер; flush отправляет накопленное в ResponseWriter.
+type exportEncoder interface {
+	encode(rb postgre.RequestFields) error
+	flush() error
+}
+
+// NewExport возвращает хендлер, выгружающий подписки потоком в CSV или NDJSON
+//
+// @Summary Выгрузить подписки в CSV или NDJSON
+// @Description Отдает все подписки под фильтрами и сортировкой списка потоком, без загрузки всей выборки в память.
+// @Description Параметры фильтров и сортировки совпадают с GET /api/v1/subscriptions, limit не учитывается. В CSV даты в формате YYYY-MM-DD.
+// @Tags subscriptions
+// @Produce text/csv,application/x-ndjson
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param format query string false "Формат выгрузки (по умолчанию csv)" Enums(csv, ndjson)
+// @Param cursor query string false "Курсор из next_cursor списка: выгрузка начнется после этой записи"
+// @Param service_name query string false "Имя сервиса"
+// @Param user_id query string false "UUID пользователя"
+// @Param active_on query string false "Дата (YYYY-MM-DD), на которую подписка активна"
+// @Param price_min query int false "Минимальная цена"
+// @Param price_max query int false "Максимальная цена"
+// @Param sort query string false "Поле сортировки: price, start_date, service_name; префикс '-' - по убыванию"
+// @Success 200 {string} string "Строки выгрузки"
+// @Failure 400 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/subscriptions/export [get]
+func NewExport(log *slog.Logger, storage Export) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.NewExport"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
+		)
+
+		log.Info("Export handler started")
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = exportFormatCSV
+		}
+		if format != exportFormatCSV && format != exportFormatNDJSON {
+			log.Info("Unknown export format", slog.String("format", format))
+			response.WriteError(w, r, response.BadRequest, "unknown format: "+format)
+			return
+		}
+
+		params, err := parseListParams(r.URL.Query())
+		if err != nil {
+			log.Info("Invalid query params", slog.String("error", err.Error()))
+			response.WriteError(w, r, response.BadRequest, err.Error())
+			return
+		}
+
+		if !scopeUserFilter(w, r, log, &params.UserID) {
+			return
+		}
+
+		rc := http.NewResponseController(w)
+
+		var (
+			enc   exportEncoder
+			count int
+		)
+
+		// start отправляет заголовки ответа; до первой строки ошибку хранилища еще можно вернуть статусом 500.
+		start := func() error {
+			w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.`+format+`"`)
+			if format == exportFormatCSV {
+				w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+				w.WriteHeader(http.StatusOK)
+				enc = newCSVEncoder(w)
+			} else {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				w.WriteHeader(http.StatusOK)
+				enc = newNDJSONEncoder(w)
+			}
+			return enc.flush()
+		}
+
+		err = storage.Export(r.Context(), params, func(rb postgre.RequestFields) error {
+			if err := r.Context().Err(); err != nil {
+				return err
+			}
+			if enc == nil {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			if err := enc.encode(rb); err != nil {
+				return err
+			}
+			count++
+			if count%exportFlushRows == 0 {
+				if err := enc.flush(); err != nil {
+					return err
+				}
+				if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			if enc == nil {
+				writeStorageError(w, r, log, err, "Failed to export subscriptions")
+				return
+			}
+			// Заголовки уже отправлены: обрываем соединение, чтобы клиент не принял неполную выгрузку за полную.
+			log.Error("Export interrupted", slog.Int("count", count), slog.String("error", err.Error()))
+			panic(http.ErrAbortHandler)
+		}
+
+		if enc == nil {
+			if err := start(); err != nil {
+				log.Error("Failed to write export", slog.String("error", err.Error()))
+				return
+			}
+		}
+		if err := enc.flush(); err != nil {
+			log.Error("Failed to write export", slog.String("error", err.Error()))
+			return
+		}
+
+		log.Info("Subscriptions exported successfully", slog.String("format", format), slog.Int("count", count))
+	}
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+// newCSVEncoder возвращает CSV-кодировщик и записывает строку заголовка.
+func newCSVEncoder(w http.ResponseWriter) *csvEncoder {
+	enc := &csvEncoder{w: csv.NewWriter(w)}
+	enc.w.Write(exportColumns)
+	return enc
+}
+
+func (e *csvEncoder) encode(rb postgre.RequestFields) error {
+	var endDate string
+	if rb.EndDate != nil {
+		endDate = rb.EndDate.Format(time.DateOnly)
+	}
+
+	return e.w.Write([]string{
+		strconv.FormatInt(rb.ID, 10),
+		rb.ServiceName,
+		strconv.FormatUint(uint64(rb.Price), 10),
+		rb.UserId,
+		rb.StartDate.Format(time.DateOnly),
+		endDate,
+		strconv.FormatInt(rb.Version, 10),
+	})
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w http.ResponseWriter) *ndjsonEncoder {
+	bw := bufio.NewWriter(w)
+	return &ndjsonEncoder{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (e *ndjsonEncoder) encode(rb postgre.RequestFields) error {
+	return e.enc.Encode(rb)
+}
+
+func (e *ndjsonEncoder) flush() error {
+	return e.w.Flush()
+}
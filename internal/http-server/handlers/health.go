@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"gotest_23.07.25/internal/health"
+	"gotest_23.07.25/internal/tracing"
+)
+
+type Readiness interface {
+	Ready(ctx context.Context) health.Report
+}
+
+type LivenessResponse struct {
+	Status string `json:"status" example:"ok"`
+}
+
+// NewLiveness возвращает хендлер, сообщающий, что процесс жив
+//
+// @Summary Проверка жизнеспособности
+// @Description Отвечает 200, пока процесс обрабатывает запросы. Не обращается к БД и не зависит от остановки сервера.
+// @Tags health
+// @Produce json
+// @Success 200 {object} LivenessResponse
+// @Router /healthz [get]
+func NewLiveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, LivenessResponse{Status: health.StatusOK})
+	}
+}
+
+// NewReadiness возвращает хендлер, проверяющий готовность сервиса принимать запросы
+//
+// @Summary Проверка готовности
+// @Description Проверяет задержку ping БД, занятость пула соединений и совпадение примененной версии миграций со встроенной в бинарник.
+// @Description С начала остановки сервера отвечает 503 со статусом draining, чтобы балансировщик перестал отправлять запросы.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func NewReadiness(log *slog.Logger, readiness Readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.NewReadiness"
+
+		report := readiness.Ready(r.Context())
+		if !report.Ready() {
+			log.Warn("Service is not ready",
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				tracing.LogAttr(r.Context()),
+				slog.String("status", report.Status),
+				slog.Any("checks", report.Checks),
+			)
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+
+		render.JSON(w, r, report)
+	}
+}
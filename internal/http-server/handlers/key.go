@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gotest_23.07.25/internal/postgre"
+)
+
+var (
+	errEmptyURLParam = errors.New("url param is empty")
+	errInvalidID     = errors.New("invalid id")
+)
+
+// subscriptionKey возвращает ключ подписки из параметров URL: {id} либо пару {service_name} и {user_id}.
+func subscriptionKey(r *http.Request) (postgre.SubscriptionKey, error) {
+	if v := chi.URLParam(r, "id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return postgre.SubscriptionKey{}, errInvalidID
+		}
+		return postgre.SubscriptionKey{ID: id}, nil
+	}
+
+	key := postgre.SubscriptionKey{
+		ServiceName: chi.URLParam(r, "service_name"),
+		UserID:      chi.URLParam(r, "user_id"),
+	}
+	if key.ServiceName == "" || key.UserID == "" {
+		return key, errEmptyURLParam
+	}
+
+	return key, nil
+}
+
+// keyAttrs возвращает атрибуты лога для ключа подписки.
+func keyAttrs(key postgre.SubscriptionKey) []any {
+	if key.ID != 0 {
+		return []any{slog.Int64("id", key.ID)}
+	}
+	return []any{slog.String("service_name", key.ServiceName), slog.String("user_id", key.UserID)}
+}
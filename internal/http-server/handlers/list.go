@@ -1,59 +1,169 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"gotest_23.07.25/internal/http-server/response"
 	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
 )
 
 type List interface {
-	List() ([]postgre.RequestFields, error)
+	List(ctx context.Context, params postgre.ListParams) (*postgre.ListPage, error)
 }
 
 type ListResponse struct {
 	Status        string                  `json:"status"`
 	Message       string                  `json:"message"`
 	Subscriptions []postgre.RequestFields `json:"subscriptions"`
+	NextCursor    string                  `json:"next_cursor,omitempty"`
+	Total         uint64                  `json:"total"`
 }
 
-// NewList возвращает хендлер, возвращающий все подписки
+// NewList возвращает хендлер, возвращающий страницу списка подписок
 //
-// @Summary Получить список всех подписок
-// @Description Возвращает все подписки
+// @Summary Получить список подписок
+// @Description Возвращает страницу подписок с фильтрами и сортировкой. Для получения следующей страницы передайте next_cursor из ответа в параметр cursor с теми же фильтрами и сортировкой.
+// @Description total - число записей под фильтрами на момент запроса страницы: если между запросами страниц записи создают или удаляют, total может не совпасть с числом записей на всех страницах.
+// @Description С JWT без роли администратора возвращаются только подписки пользователя из токена; user_id другого пользователя - 403.
 // @Tags subscriptions
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 1000)"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Param service_name query string false "Имя сервиса"
+// @Param user_id query string false "UUID пользователя"
+// @Param active_on query string false "Дата (YYYY-MM-DD), на которую подписка активна"
+// @Param price_min query int false "Минимальная цена"
+// @Param price_max query int false "Максимальная цена"
+// @Param sort query string false "Поле сортировки: price, start_date, service_name; префикс '-' - по убыванию"
 // @Success 200 {object} ListResponse
-// @Failure 500 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
 // @Router /api/v1/subscriptions [get]
 func NewList(log *slog.Logger, storage List) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "http-server.handlers.NewList"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
 		)
 
 		log.Info("List handler started")
 
-		subscriptions, err := storage.List()
+		params, err := parseListParams(r.URL.Query())
 		if err != nil {
-			log.Error("Failed to list subscriptions", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, response.Error("internal error"))
+			log.Info("Invalid query params", slog.String("error", err.Error()))
+			response.WriteError(w, r, response.BadRequest, err.Error())
 			return
 		}
 
-		log.Info("Subscriptions listed successfully")
-		w.WriteHeader(http.StatusOK)
+		if !scopeUserFilter(w, r, log, &params.UserID) {
+			return
+		}
+
+		page, err := storage.List(r.Context(), params)
+		if err != nil {
+			writeStorageError(w, r, log, err, "Failed to list subscriptions")
+			return
+		}
+
+		log.Info("Subscriptions listed successfully", slog.Int("count", len(page.Subscriptions)))
 		render.JSON(w, r, ListResponse{
 			Status:        "success",
 			Message:       "Subscriptions listed successfully",
-			Subscriptions: subscriptions,
+			Subscriptions: page.Subscriptions,
+			NextCursor:    page.NextCursor,
+			Total:         page.Total,
 		})
 	}
 }
+
+// parseListParams разбирает query-параметры списка подписок.
+func parseListParams(query url.Values) (postgre.ListParams, error) {
+	params := postgre.ListParams{
+		Limit:       postgre.DefaultListLimit,
+		ServiceName: query.Get("service_name"),
+		UserID:      query.Get("user_id"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > postgre.MaxListLimit {
+			return params, fmt.Errorf("limit must be between 1 and %d", postgre.MaxListLimit)
+		}
+		params.Limit = limit
+	}
+
+	if v := query.Get("active_on"); v != "" {
+		activeOn, err := parseDate(v)
+		if err != nil {
+			return params, fmt.Errorf("invalid active_on: %s", v)
+		}
+		params.ActiveOn = &activeOn
+	}
+
+	var err error
+	if params.PriceMin, err = parsePrice(query.Get("price_min")); err != nil {
+		return params, fmt.Errorf("invalid price_min: %s", query.Get("price_min"))
+	}
+	if params.PriceMax, err = parsePrice(query.Get("price_max")); err != nil {
+		return params, fmt.Errorf("invalid price_max: %s", query.Get("price_max"))
+	}
+
+	if v := query.Get("sort"); v != "" {
+		params.Desc = strings.HasPrefix(v, "-")
+		params.Sort = strings.TrimPrefix(v, "-")
+		switch params.Sort {
+		case postgre.SortByPrice, postgre.SortByStartDate, postgre.SortByServiceName:
+		default:
+			return params, fmt.Errorf("unknown sort field: %s", params.Sort)
+		}
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := postgre.DecodeListCursor(v, params.Sort, params.Desc)
+		if err != nil {
+			return params, err
+		}
+		params.Cursor = cursor
+	}
+
+	return params, nil
+}
+
+// parseDate разбирает дату в формате YYYY-MM-DD или RFC 3339.
+func parseDate(v string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func parsePrice(v string) (*uint16, error) {
+	if v == "" {
+		return nil, nil
+	}
+	price, err := strconv.ParseUint(v, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	p := uint16(price)
+	return &p, nil
+}
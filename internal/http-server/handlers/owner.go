@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"gotest_23.07.25/internal/http-server/middlewares/auth"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// errForeignSubscription - детали ответа 403 при обращении к подпискам другого пользователя.
+const errForeignSubscription = "subscription belongs to another user"
+
+// restrictedUser возвращает пользователя, подписками которого ограничен клиент.
+// Ключи API и JWT с ролью администратора не ограничены.
+func restrictedUser(r *http.Request) (string, bool) {
+	id, ok := auth.FromContext(r.Context())
+	if !ok || id.UserID == "" {
+		return "", false
+	}
+	return id.UserID, true
+}
+
+// sameUser сравнивает UUID пользователей без учета регистра.
+func sameUser(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// scopeUserFilter ограничивает фильтр user_id пользователем клиента: пустой фильтр заменяется на него,
+// фильтр по другому пользователю запрещен. Возвращает false, если ответ 403 уже записан.
+func scopeUserFilter(w http.ResponseWriter, r *http.Request, log *slog.Logger, userID *string) bool {
+	user, ok := restrictedUser(r)
+	if !ok {
+		return true
+	}
+
+	if *userID != "" && !sameUser(*userID, user) {
+		writeForbidden(w, r, log, "user_id filter must match the authenticated user")
+		return false
+	}
+
+	*userID = user
+	return true
+}
+
+// authorizeKey проверяет, что подписка с ключом key принадлежит пользователю клиента.
+// Для ключа по id владелец читается из хранилища. Возвращает false, если ответ уже записан.
+func authorizeKey(w http.ResponseWriter, r *http.Request, log *slog.Logger, storage Read, key postgre.SubscriptionKey) bool {
+	user, ok := restrictedUser(r)
+	if !ok {
+		return true
+	}
+
+	owner := key.UserID
+	if key.ID != 0 {
+		rb, err := storage.Read(r.Context(), key)
+		if err != nil {
+			writeStorageError(w, r, log.With(keyAttrs(key)...), err, "Failed to read record owner")
+			return false
+		}
+		owner = rb.UserId
+	}
+
+	if !sameUser(owner, user) {
+		writeForbidden(w, r, log.With(keyAttrs(key)...), errForeignSubscription)
+		return false
+	}
+
+	return true
+}
+
+// writeForbidden отвечает 403 на обращение к данным другого пользователя.
+func writeForbidden(w http.ResponseWriter, r *http.Request, log *slog.Logger, detail string) {
+	log.Info("Access to another user's data denied", slog.String("detail", detail))
+	response.WriteError(w, r, response.Forbidden, detail)
+}
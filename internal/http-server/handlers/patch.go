@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// mergePatchContentType - тип содержимого JSON Merge Patch (RFC 7396).
+const mergePatchContentType = "application/merge-patch+json"
+
+var errInvalidPatch = errors.New("invalid merge patch body")
+
+type Patch interface {
+	// Read нужен для проверки владельца подписки, к которой обращаются по id.
+	Read
+	Patch(ctx context.Context, key postgre.SubscriptionKey, patch postgre.PatchFields, version int64) (*postgre.RequestFields, error)
+}
+
+// PatchRequestBody описывает тело PATCH-запроса для документации.
+// Запрос разбирается в decodeMergePatch: отсутствующие поля не меняются, end_date: null снимает дату окончания.
+type PatchRequestBody struct {
+	Price     *uint16    `json:"price,omitempty" example:"100"`
+	StartDate *time.Time `json:"start_date,omitempty" example:"2025-01-01T00:00:00Z"`
+	EndDate   *time.Time `json:"end_date,omitempty" example:"2025-12-31T00:00:00Z" extensions:"x-nullable"`
+}
+
+// NewPatch возвращает хендлер, частично изменяющий информацию о подписке
+//
+// @Summary Частично изменить информацию о подписке
+// @Description Принимает JSON Merge Patch (RFC 7396): меняются только переданные поля price, start_date, end_date.
+// @Description end_date: null снимает дату окончания. Возвращает полную запись после изменения.
+// @Description Если периодов подписки несколько, используется последний по start_date
+// @Tags subscriptions
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param service_name path string true "Название сервиса"
+// @Param user_id path string true "ID пользователя"
+// @Param patch body PatchRequestBody true "Изменяемые поля"
+// @Param If-Match header string false "ETag из ответа GET; запрос выполняется только для этой версии записи"
+// @Success 200 {object} response.Response
+// @Header 200 {string} ETag "Новая версия записи"
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Failure 412 {object} response.Problem
+// @Failure 415 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/subscriptions/{service_name}/{user_id} [patch]
+func NewPatch(log *slog.Logger, storage Patch) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.NewPatch"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
+		)
+
+		log.Info("Patch handler started")
+
+		key, err := subscriptionKey(r)
+		if err != nil {
+			log.Info("Invalid url params", slog.String("error", err.Error()))
+			response.WriteError(w, r, response.BadRequest, err.Error())
+			return
+		}
+
+		if !authorizeKey(w, r, log, storage, key) {
+			return
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != "" {
+			mediaType, _, _ := mime.ParseMediaType(ct)
+			if mediaType != mergePatchContentType && mediaType != "application/json" {
+				log.Info("Unsupported content type", slog.String("content_type", ct))
+				response.WriteError(w, r, response.UnsupportedMediaType, "content type must be "+mergePatchContentType)
+				return
+			}
+		}
+
+		patch, errs := decodeMergePatch(r.Body)
+		if errs != nil {
+			writeValidationError(w, r, log, errs)
+			return
+		}
+
+		version, err := ifMatchVersion(r)
+		if err != nil {
+			writePreconditionFailed(w, r, log, err)
+			return
+		}
+
+		rb, err := storage.Patch(r.Context(), key, patch, version)
+		if err != nil {
+			writeStorageError(w, r, log.With(keyAttrs(key)...), err, "Failed to patch record")
+			return
+		}
+
+		log.Info("Record patched successfully", slog.Any("record", rb))
+		w.Header().Set("ETag", etag(rb.Version))
+		render.JSON(w, r, response.OK("Record patched successfully", rb))
+	}
+}
+
+// NewPatchByID возвращает хендлер, частично изменяющий информацию о подписке по ее id
+//
+// @Summary Частично изменить информацию о подписке по id
+// @Description Принимает JSON Merge Patch (RFC 7396), см. PATCH /api/v1/subscriptions/{service_name}/{user_id}
+// @Tags subscriptions
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path int true "ID подписки"
+// @Param patch body PatchRequestBody true "Изменяемые поля"
+// @Param If-Match header string false "ETag из ответа GET; запрос выполняется только для этой версии записи"
+// @Success 200 {object} response.Response
+// @Header 200 {string} ETag "Новая версия записи"
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Failure 412 {object} response.Problem
+// @Failure 415 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/subscriptions/{id} [patch]
+func NewPatchByID(log *slog.Logger, storage Patch) http.HandlerFunc {
+	return NewPatch(log, storage)
+}
+
+// decodeMergePatch разбирает тело JSON Merge Patch в postgre.PatchFields.
+// Поля, которые нельзя изменить или удалить, и некорректные значения возвращаются как ошибки валидации.
+// Поле version игнорируется: версия передается в If-Match.
+func decodeMergePatch(body io.Reader) (postgre.PatchFields, validation.Errors) {
+	var (
+		patch postgre.PatchFields
+		doc   map[string]json.RawMessage
+		errs  validation.Errors
+	)
+
+	if errs := validation.DecodeJSON(body, &doc); errs != nil {
+		return patch, errs
+	}
+	if doc == nil {
+		return patch, validation.Errors{{Code: validation.CodeInvalidJSON, Message: errInvalidPatch.Error()}}
+	}
+
+	for field, value := range doc {
+		null := string(value) == "null"
+
+		switch field {
+		case "price":
+			if null {
+				errs = append(errs, cannotBeRemoved(field))
+				continue
+			}
+			var price uint16
+			if err := json.Unmarshal(value, &price); err != nil {
+				errs = append(errs, invalidType(field, "whole number in range"))
+				continue
+			}
+			errs = append(errs, validation.Price(field, price)...)
+			patch.Price = &price
+		case "start_date":
+			if null {
+				errs = append(errs, cannotBeRemoved(field))
+				continue
+			}
+			var startDate time.Time
+			if err := json.Unmarshal(value, &startDate); err != nil {
+				errs = append(errs, invalidType(field, "date string"))
+				continue
+			}
+			patch.StartDate = &startDate
+		case "end_date":
+			patch.EndDateSet = true
+			if null {
+				continue
+			}
+			var endDate time.Time
+			if err := json.Unmarshal(value, &endDate); err != nil {
+				errs = append(errs, invalidType(field, "date string"))
+				continue
+			}
+			patch.EndDate = &endDate
+		case "version":
+			// Версию передает If-Match; поле из ответа GET игнорируется, как и при импорте.
+		case "id", "service_name", "user_id":
+			errs = append(errs, validation.FieldError{
+				Field:   field,
+				Code:    validation.CodeReadOnly,
+				Message: fmt.Sprintf("%s cannot be changed", field),
+			})
+		default:
+			errs = append(errs, validation.FieldError{
+				Field:   field,
+				Code:    validation.CodeUnknownField,
+				Message: fmt.Sprintf("unknown field %s", field),
+			})
+		}
+	}
+
+	if patch.StartDate != nil && patch.EndDate != nil && patch.EndDate.Before(*patch.StartDate) {
+		errs = append(errs, validation.Period(*patch.StartDate, patch.EndDate)...)
+	}
+
+	// порядок полей в map случаен: сортируем, чтобы ответ был стабильным
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+
+	return patch, errs
+}
+
+func cannotBeRemoved(field string) validation.FieldError {
+	return validation.FieldError{
+		Field:   field,
+		Code:    validation.CodeRequired,
+		Message: fmt.Sprintf("%s cannot be removed", field),
+	}
+}
+
+func invalidType(field, typ string) validation.FieldError {
+	return validation.FieldError{
+		Field:   field,
+		Code:    validation.CodeInvalidType,
+		Message: fmt.Sprintf("%s must be a %s", field, typ),
+	}
+}
@@ -1,13 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
-	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/tracing"
 )
 
 type RangeRequestBody struct {
@@ -17,6 +19,17 @@ type RangeRequestBody struct {
 	UserID      string    `json:"user_id" example:"b1d4c0ec-9a4a-4e3a-9fdd-5e27d0be16fa"`
 }
 
+// Validate проверяет границы периода и необязательные фильтры service_name и user_id.
+func (rb RangeRequestBody) Validate() validation.Errors {
+	var errs validation.Errors
+
+	errs = append(errs, validation.Range(rb.StartDate, rb.EndDate)...)
+	errs = append(errs, validation.ServiceName("service_name", rb.ServiceName, false)...)
+	errs = append(errs, validation.UUID("user_id", rb.UserID, false)...)
+
+	return errs
+}
+
 type RangeResponse struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
@@ -24,65 +37,66 @@ type RangeResponse struct {
 }
 
 type RangePrice interface {
-	RangePrice(start_date time.Time, end_date time.Time, service_name string, user_id string) (uint64, error)
+	RangePrice(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string) (uint64, error)
 }
 
 // NewRangePrice возвращает хендлер, возвращающий стоимость подписок в выбранном периоде
 //
 // @Summary Получить общую стоимость подписок за период
 // @Description Подсчитывает общую стоимость подписок по start_date, end_date, service_name, user_id. service_name и user_id можно передать пустыми.
+// @Description Цена подписки считается ежемесячной: она умножается на число календарных месяцев, в которых подписка активна в периоде. Неполный месяц оплачивается целиком.
+// @Description Месяц оплачивается для пары service_name и user_id один раз: если в нем активны несколько периодов подписки, берется цена периода, начавшегося позже, а более ранний период в этом месяце не учитывается.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Param subscription_filter body RangeRequestBody true "фильтры для рассчета"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом и телом возвращает сохраненный ответ"
 // @Success 200 {object} RangeResponse
-// @Failure 400 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Failure 400 {object} response.Problem
+// @Failure 422 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
 // @Router /api/v1/subscriptions/range-price [post]
 func NewRangePrice(log *slog.Logger, storage RangePrice) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "http-server.handlers.NewRangePrice"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
 		)
 
 		log.Info("RangePrice handler started")
 
 		var rb RangeRequestBody
-		if err := render.DecodeJSON(r.Body, &rb); err != nil {
-			log.Error("Failed to decode request body", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("invalid request body"))
+		if errs := validation.DecodeJSON(r.Body, &rb); errs != nil {
+			writeValidationError(w, r, log, errs)
 			return
 		}
 
 		log.Debug("Decoded request body", slog.Any("request_body", rb))
 
-		if rb.StartDate.IsZero() || rb.EndDate.IsZero() {
-			log.Info("url param is empty")
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("url param is empty"))
+		if errs := rb.Validate(); errs != nil {
+			writeValidationError(w, r, log, errs)
 			return
 		}
 
-		if rb.StartDate.After(rb.EndDate) {
-			log.Info("Start-date is after end-date")
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("start date cannot be after end date"))
+		if !scopeUserFilter(w, r, log, &rb.UserID) {
 			return
 		}
 
-		ResPrice, err := storage.RangePrice(rb.StartDate, rb.EndDate, rb.ServiceName, rb.UserID)
+		ResPrice, err := storage.RangePrice(r.Context(), rb.StartDate, rb.EndDate, rb.ServiceName, rb.UserID)
 		if err != nil {
-			log.Error("Failed to get range price", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, response.Error("internal error"))
+			writeStorageError(w, r, log, err, "Failed to get range price")
 			return
 		}
 		log.Info("Get range price successfully", slog.Uint64("price", ResPrice))
-		w.WriteHeader(http.StatusOK)
 		render.JSON(w, r, RangeResponse{
 			Status:  "success",
 			Message: "Get range price successfully",
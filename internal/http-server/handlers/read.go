@@ -1,74 +1,105 @@
 package handlers
 
 import (
-	"database/sql"
-	"errors"
+	"context"
 	"log/slog"
 	"net/http"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"gotest_23.07.25/internal/http-server/response"
 	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
 )
 
 type Read interface {
-	Read(service_name, user_id string) (*postgre.RequestFields, error)
+	Read(ctx context.Context, key postgre.SubscriptionKey) (*postgre.RequestFields, error)
 }
 
 // NewRead возвращает хендлер, возвращающий информацию о выбранной подписке
 //
 // @Summary Получить информацию о подписке
-// @Description Возвращает информацию о подписке по service_name и user_id
+// @Description Возвращает информацию о подписке по service_name и user_id. Если периодов подписки несколько, используется последний по start_date
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Param service_name path string true "Имя сервиса"
 // @Param user_id path string true "UUID пользователя"
 // @Success 200 {object} response.Response
-// @Failure 400 {object} response.Response
-// @Failure 404 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Header 200 {string} ETag "Версия записи для If-Match"
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
 // @Router /api/v1/subscriptions/{service_name}/{user_id} [get]
 func NewRead(log *slog.Logger, storage Read) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "http-server.handlers.NewRead"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
 		)
 
 		log.Info("Read handler started")
 
-		serviceName := chi.URLParam(r, "service_name")
-		userID := chi.URLParam(r, "user_id")
+		key, err := subscriptionKey(r)
+		if err != nil {
+			log.Info("Invalid url params", slog.String("error", err.Error()))
+			response.WriteError(w, r, response.BadRequest, err.Error())
+			return
+		}
 
-		if serviceName == "" || userID == "" {
-			log.Info("url param is empty")
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("url param is empty"))
+		user, restricted := restrictedUser(r)
+		if restricted && key.ID == 0 && !sameUser(key.UserID, user) {
+			writeForbidden(w, r, log.With(keyAttrs(key)...), errForeignSubscription)
 			return
 		}
 
-		rb, err := storage.Read(serviceName, userID)
+		rb, err := storage.Read(r.Context(), key)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				log.Warn("record not found: %s, %s", serviceName, userID)
-				w.WriteHeader(http.StatusNotFound)
-				render.JSON(w, r, response.Error("record not found"))
-				return
-			}
-			log.Error("Failed to read record", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, response.Error("internal error"))
+			writeStorageError(w, r, log.With(keyAttrs(key)...), err, "Failed to read record")
+			return
+		}
+
+		if restricted && !sameUser(rb.UserId, user) {
+			writeForbidden(w, r, log.With(keyAttrs(key)...), errForeignSubscription)
 			return
 		}
 
 		log.Info("Record read successfully", slog.Any("record", rb))
-		w.WriteHeader(http.StatusOK)
+		w.Header().Set("ETag", etag(rb.Version))
 		render.JSON(w, r, response.OK("Record read successfully", rb))
 	}
 
 }
+
+// NewReadByID возвращает хендлер, возвращающий информацию о подписке по ее id
+//
+// @Summary Получить информацию о подписке по id
+// @Description Возвращает информацию о подписке по id. Подписка другого пользователя для клиента, ограниченного своими подписками, получает 403
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path int true "ID подписки"
+// @Success 200 {object} response.Response
+// @Header 200 {string} ETag "Версия записи для If-Match"
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/subscriptions/{id} [get]
+func NewReadByID(log *slog.Logger, storage Read) http.HandlerFunc {
+	return NewRead(log, storage)
+}
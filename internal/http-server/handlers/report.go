@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// report group fields:
+const (
+	groupByServiceName = "service_name"
+	groupByUserID      = "user_id"
+	groupByMonth       = "month"
+)
+
+type ReportRequestBody struct {
+	RangeRequestBody
+	GroupBy []string `json:"group_by" example:"service_name,month"`
+}
+
+type ReportResponse struct {
+	Status  string                `json:"status"`
+	Message string                `json:"message"`
+	Price   uint64                `json:"price"`
+	Groups  []postgre.ReportGroup `json:"groups"`
+}
+
+type Report interface {
+	Report(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy postgre.ReportGroupBy) ([]postgre.ReportGroup, error)
+}
+
+// NewReport возвращает хендлер, возвращающий расходы на подписки за период с группировкой
+//
+// @Summary Получить отчет о расходах на подписки за период
+// @Description Считает расходы так же, как range-price, но разбивает их на группы по любому сочетанию полей service_name, user_id и month (календарный месяц).
+// @Description Для каждой группы возвращается сумма и количество подписок. Без group_by возвращается одна группа с общей суммой.
+// @Description Количество считает только периоды, за которые в группе начислен хотя бы один месяц: период, все месяцы которого перекрыты более поздним периодом той же подписки, не учитывается.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param report_filter body ReportRequestBody true "фильтры и поля группировки"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом и телом возвращает сохраненный ответ"
+// @Success 200 {object} ReportResponse
+// @Failure 400 {object} response.Problem
+// @Failure 422 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/subscriptions/report [post]
+func NewReport(log *slog.Logger, storage Report) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "http-server.handlers.NewReport"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
+		)
+
+		log.Info("Report handler started")
+
+		var rb ReportRequestBody
+		if errs := validation.DecodeJSON(r.Body, &rb); errs != nil {
+			writeValidationError(w, r, log, errs)
+			return
+		}
+
+		log.Debug("Decoded request body", slog.Any("request_body", rb))
+
+		if errs := rb.Validate(); errs != nil {
+			writeValidationError(w, r, log, errs)
+			return
+		}
+
+		if !scopeUserFilter(w, r, log, &rb.UserID) {
+			return
+		}
+
+		var groupBy postgre.ReportGroupBy
+		for _, field := range rb.GroupBy {
+			switch field {
+			case groupByServiceName:
+				groupBy.ServiceName = true
+			case groupByUserID:
+				groupBy.UserID = true
+			case groupByMonth:
+				groupBy.Month = true
+			default:
+				writeValidationError(w, r, log, validation.Errors{{
+					Field:   "group_by",
+					Code:    validation.CodeInvalidValue,
+					Message: "unknown group_by field: " + field,
+				}})
+				return
+			}
+		}
+
+		groups, err := storage.Report(r.Context(), rb.StartDate, rb.EndDate, rb.ServiceName, rb.UserID, groupBy)
+		if err != nil {
+			writeStorageError(w, r, log, err, "Failed to get report")
+			return
+		}
+
+		var total uint64
+		for _, g := range groups {
+			total += g.Price
+		}
+
+		log.Info("Get report successfully", slog.Int("groups", len(groups)), slog.Uint64("price", total))
+		render.JSON(w, r, ReportResponse{
+			Status:  "success",
+			Message: "Get report successfully",
+			Price:   total,
+			Groups:  groups,
+		})
+	}
+}
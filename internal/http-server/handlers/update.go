@@ -1,20 +1,22 @@
 package handlers
 
 import (
-	"database/sql"
-	"errors"
+	"context"
 	"log/slog"
 	"net/http"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/validation"
 	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
 )
 
 type Update interface {
-	Update(service_name, user_id string, rb postgre.RequestUpdateFields) error
+	// Read нужен для проверки владельца подписки, к которой обращаются по id.
+	Read
+	Update(ctx context.Context, key postgre.SubscriptionKey, rb postgre.RequestUpdateFields, version int64) (int64, error)
 }
 
 type UpdateResponse struct {
@@ -26,68 +28,79 @@ type UpdateResponse struct {
 // NewUpdate возвращает хендлер, изменяющий информацию о подписке
 //
 // @Summary Изменить информацию о подписке
-// @Description Возвращает информацию о подписке по service_name и user_id
+// @Description Возвращает информацию о подписке по service_name и user_id. Если периодов подписки несколько, используется последний по start_date
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Param service_name path string true "Имя подписки изменяемой записи"
 // @Param user_id path string true "UUID пользователя изменяемой записи"
 // @Param newFields body postgre.RequestUpdateFields true "Новая информация о подписке"
+// @Param If-Match header string false "ETag из ответа GET; запрос выполняется только для этой версии записи"
 // @Success 200 {object} response.Response
-// @Failure 400 {object} response.Response
-// @Failure 404 {object} response.Response
-// @Failure 500 {object} response.Response
+// @Header 200 {string} ETag "Новая версия записи"
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Failure 412 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
 // @Router /api/v1/subscriptions/{service_name}/{user_id} [put]
 func NewUpdate(log *slog.Logger, storage Update) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "http-server.handlers.NewUpdate"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			tracing.LogAttr(r.Context()),
 		)
 
 		log.Info("Update handler started")
 
-		serviceName := chi.URLParam(r, "service_name")
-		userID := chi.URLParam(r, "user_id")
+		key, err := subscriptionKey(r)
+		if err != nil {
+			log.Info("Invalid url params", slog.String("error", err.Error()))
+			response.WriteError(w, r, response.BadRequest, err.Error())
+			return
+		}
 
-		if serviceName == "" || userID == "" {
-			log.Info("url param is empty")
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("url param is empty"))
+		if !authorizeKey(w, r, log, storage, key) {
 			return
 		}
 
 		var rb postgre.RequestUpdateFields
 
-		if err := render.DecodeJSON(r.Body, &rb); err != nil {
-			log.Error("Failed to decode request body", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusBadRequest)
-			render.JSON(w, r, response.Error("invalid request body"))
+		if errs := validation.DecodeJSON(r.Body, &rb); errs != nil {
+			writeValidationError(w, r, log, errs)
 			return
 		}
 
 		log.Debug("Decoded request body", slog.Any("request_body", rb))
 
-		if err := storage.Update(serviceName, userID, rb); err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				log.Warn("record not found",
-					slog.String("service_name", serviceName),
-					slog.String("user_id", userID),
-				)
-				w.WriteHeader(http.StatusNotFound)
-				render.JSON(w, r, response.Error("record not found"))
-				return
-			}
-			log.Error("Failed to update record", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusInternalServerError)
-			render.JSON(w, r, response.Error("internal error"))
+		if errs := validation.Update(rb); errs != nil {
+			writeValidationError(w, r, log, errs)
+			return
+		}
+
+		version, err := ifMatchVersion(r)
+		if err != nil {
+			writePreconditionFailed(w, r, log, err)
+			return
+		}
+
+		version, err = storage.Update(r.Context(), key, rb, version)
+		if err != nil {
+			writeStorageError(w, r, log.With(keyAttrs(key)...), err, "Failed to update record")
 			return
 		}
 
 		log.Info("Record updated successfully", slog.Any("record", rb))
-		w.WriteHeader(http.StatusOK)
+		w.Header().Set("ETag", etag(version))
 		render.JSON(w, r, UpdateResponse{
 			Status:  "success",
 			Message: "record updated successfully",
@@ -95,3 +108,31 @@ func NewUpdate(log *slog.Logger, storage Update) http.HandlerFunc {
 		})
 	}
 }
+
+// NewUpdateByID возвращает хендлер, изменяющий информацию о подписке по ее id
+//
+// @Summary Изменить информацию о подписке по id
+// @Description Заменяет цену и даты подписки с указанным id
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path int true "ID подписки"
+// @Param newFields body postgre.RequestUpdateFields true "Новая информация о подписке"
+// @Param If-Match header string false "ETag из ответа GET; запрос выполняется только для этой версии записи"
+// @Success 200 {object} response.Response
+// @Header 200 {string} ETag "Новая версия записи"
+// @Failure 400 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Failure 412 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Failure 500 {object} response.Problem
+// @Failure 503 {object} response.Problem
+// @Router /api/v1/subscriptions/{id} [put]
+func NewUpdateByID(log *slog.Logger, storage Update) http.HandlerFunc {
+	return NewUpdate(log, storage)
+}
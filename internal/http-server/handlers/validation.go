@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/validation"
+)
+
+// writeValidationError отвечает 400 со списком ошибок валидации.
+func writeValidationError(w http.ResponseWriter, r *http.Request, log *slog.Logger, errs validation.Errors) {
+	log.Info("Invalid request", slog.String("error", errs.Error()))
+	response.WriteProblem(w, r, response.ValidationProblem(errs))
+}
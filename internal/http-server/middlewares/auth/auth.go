@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gotest_23.07.25/internal/http-server/middlewares/logger"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/apikey"
+	"gotest_23.07.25/internal/lib/token"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// HeaderKey - заголовок, в котором клиент передает ключ API.
+const HeaderKey = "X-API-Key"
+
+// bearerPrefix - схема заголовка Authorization для JWT.
+const bearerPrefix = "Bearer "
+
+// bootstrapKeyName - имя, под которым в логах появляется ключ администратора из конфига.
+const bootstrapKeyName = "bootstrap-admin"
+
+// KeyStore ищет действующий ключ по его хешу.
+type KeyStore interface {
+	APIKeyByHash(ctx context.Context, hash string) (*postgre.APIKey, error)
+}
+
+// TokenVerifier проверяет JWT из заголовка Authorization.
+type TokenVerifier interface {
+	Verify(raw string) (token.Claims, error)
+}
+
+// Options задает способы аутентификации. Нужен хотя бы один из Keys и Tokens.
+type Options struct {
+	// Keys - хранилище ключей API; nil - ключи API не принимаются.
+	Keys KeyStore
+	// AdminKey - ключ администратора из конфига с областью admin; пустой AdminKey не принимается.
+	AdminKey string
+	// Tokens проверяет JWT; nil - JWT не принимаются.
+	Tokens TokenVerifier
+}
+
+// Enabled сообщает, включен ли хотя бы один способ аутентификации.
+func (o Options) Enabled() bool {
+	return o.Keys != nil || o.Tokens != nil
+}
+
+// Identity - клиент, от имени которого выполняется запрос.
+type Identity struct {
+	// KeyID - id ключа API; 0 для ключа администратора из конфига и для JWT.
+	KeyID  int64
+	Name   string
+	Scopes []string
+	// UserID - пользователь из JWT без роли администратора. Если задан, клиенту доступны только подписки этого пользователя.
+	UserID string
+	// Token - клиент прошел аутентификацию по JWT; Name в этом случае - значение claim пользователя.
+	Token bool
+}
+
+// Subject возвращает строку, однозначно определяющую клиента, например для разделения ключей идемпотентности.
+func (id Identity) Subject() string {
+	if id.Token {
+		return "user:" + id.Name
+	}
+	return "key:" + strconv.FormatInt(id.KeyID, 10)
+}
+
+type identityKey struct{}
+
+// FromContext возвращает клиента, прошедшего аутентификацию.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// WithIdentity возвращает контекст с клиентом id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// New возвращает middleware, пропускающее только запросы с действующим ключом в заголовке X-API-Key
+// или JWT в заголовке Authorization: Bearer. Если переданы оба, проверяется ключ API.
+// Клиент сохраняется в контексте запроса и добавляется в лог запроса.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		log.Info("auth middleware enabled",
+			slog.Bool("api_keys", opts.Keys != nil),
+			slog.Bool("jwt", opts.Tokens != nil),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			reqLog := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				tracing.LogAttr(r.Context()),
+			)
+
+			key := r.Header.Get(HeaderKey)
+			bearer, hasBearer := strings.CutPrefix(r.Header.Get("Authorization"), bearerPrefix)
+
+			var (
+				id  Identity
+				err error
+			)
+			switch {
+			case key != "" && opts.Keys != nil:
+				id, err = IdentifyKey(r.Context(), opts, key)
+				if errors.Is(err, postgre.ErrNotFound) {
+					reqLog.Info("Invalid API key")
+					response.WriteError(w, r, response.Unauthorized, "invalid or revoked API key")
+					return
+				}
+				if err != nil {
+					reqLog.Error("Failed to look up API key", slog.String("error", err.Error()))
+					response.WriteError(w, r, response.Unavailable, "cannot verify API key")
+					return
+				}
+			case hasBearer && opts.Tokens != nil:
+				id, err = IdentifyToken(opts, bearer)
+				if err != nil {
+					reqLog.Info("Invalid token", slog.String("error", err.Error()))
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					response.WriteError(w, r, response.Unauthorized, err.Error())
+					return
+				}
+			default:
+				if opts.Tokens != nil {
+					w.Header().Set("WWW-Authenticate", "Bearer")
+				}
+				response.WriteError(w, r, response.Unauthorized, "missing credentials: "+accepted(opts))
+				return
+			}
+
+			attrs := []slog.Attr{slog.String("auth_subject", id.Subject())}
+			if id.Token {
+				attrs = append(attrs, slog.String("user_id", id.Name), slog.Bool("admin", id.UserID == ""))
+			} else {
+				attrs = append(attrs, slog.Int64("api_key_id", id.KeyID), slog.String("api_key_name", id.Name))
+			}
+			logger.AddAttrs(r.Context(), attrs...)
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Require возвращает middleware, пропускающее только клиентов с областью доступа scope.
+// Подключается после New.
+func Require(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			id, ok := FromContext(r.Context())
+			if !ok {
+				response.WriteError(w, r, response.Unauthorized, "authentication required")
+				return
+			}
+			if !apikey.HasScope(id.Scopes, scope) {
+				response.WriteError(w, r, response.Forbidden, "credentials have no "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// accepted описывает, какие учетные данные ожидаются, для ответа 401.
+func accepted(opts Options) string {
+	switch {
+	case opts.Keys != nil && opts.Tokens != nil:
+		return HeaderKey + " header or bearer token is required"
+	case opts.Tokens != nil:
+		return "bearer token is required"
+	default:
+		return HeaderKey + " header is required"
+	}
+}
+
+// IdentifyToken возвращает клиента по JWT с областями доступа к подпискам и отчетам. Пользователь без роли
+// администратора ограничен своими подписками, администратор - нет. Область admin для управления ключами API
+// токенам не выдается. opts.Tokens не должен быть nil.
+func IdentifyToken(opts Options, raw string) (Identity, error) {
+	claims, err := opts.Tokens.Verify(raw)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	id := Identity{
+		Name:   claims.UserID,
+		Scopes: []string{apikey.ScopeSubscriptionsRead, apikey.ScopeSubscriptionsWrite, apikey.ScopeReportsRead},
+		Token:  true,
+	}
+	if !claims.Admin {
+		id.UserID = claims.UserID
+	}
+
+	return id, nil
+}
+
+// IdentifyKey находит клиента по ключу API. Для неизвестного или отозванного ключа возвращает postgre.ErrNotFound.
+// opts.Keys не должен быть nil.
+func IdentifyKey(ctx context.Context, opts Options, key string) (Identity, error) {
+	if opts.AdminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(opts.AdminKey)) == 1 {
+		return Identity{Name: bootstrapKeyName, Scopes: []string{apikey.ScopeAdmin}}, nil
+	}
+
+	k, err := opts.Keys.APIKeyByHash(ctx, apikey.Hash(key))
+	if err != nil {
+		return Identity{}, err
+	}
+
+	return Identity{KeyID: k.ID, Name: k.Name, Scopes: k.Scopes}, nil
+}
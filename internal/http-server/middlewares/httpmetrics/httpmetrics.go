@@ -0,0 +1,54 @@
+package httpmetrics
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"gotest_23.07.25/internal/metrics"
+)
+
+// unmatchedRoute - метка маршрута для запросов, не попавших ни в один маршрут,
+// чтобы произвольные пути не раздували число временных рядов.
+const unmatchedRoute = "unmatched"
+
+// New возвращает middleware, считающее запросы и их длительность по методу, шаблону маршрута chi и статусу.
+// Подключается к корневому роутеру: шаблон маршрута известен только после обработки запроса.
+func New(log *slog.Logger, m *metrics.Metrics) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log.With(
+			slog.String("component", "middleware/httpmetrics"),
+		).Info("http metrics middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			m.HTTPInFlight.Inc()
+			t1 := time.Now()
+			defer func() {
+				m.HTTPInFlight.Dec()
+
+				route := unmatchedRoute
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					route = rctx.RoutePattern()
+				}
+
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				labels := []string{r.Method, route, strconv.Itoa(status)}
+				m.HTTPRequests.WithLabelValues(labels...).Inc()
+				m.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(t1).Seconds())
+			}()
+
+			next.ServeHTTP(ww, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,275 @@
+package idempotency
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gotest_23.07.25/internal/http-server/middlewares/auth"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// headers:
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+// maxKeyLength - максимальная длина значения Idempotency-Key.
+const maxKeyLength = 255
+
+// maxBodySize - наибольшее тело запроса с ключом идемпотентности; не меньше лимита пакетной загрузки.
+const maxBodySize = 32 << 20
+
+// Options - настройки хранилища ответов.
+type Options struct {
+	// TTL - время хранения ответа.
+	TTL time.Duration
+	// MaxEntries - наибольшее число сохраненных ответов; 0 - без ограничения.
+	MaxEntries int
+	// MaxBytes - наибольший суммарный размер тел сохраненных ответов; 0 - без ограничения.
+	MaxBytes int64
+}
+
+// entry - сохраненный результат запроса с ключом идемпотентности.
+// Пока запрос выполняется, done равен false.
+type entry struct {
+	scope   string
+	hash    [sha256.Size]byte
+	done    bool
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// store хранит ответы по ключам идемпотентности в памяти процесса. При превышении MaxEntries или MaxBytes
+// вытесняются давно не запрошенные завершенные записи.
+type store struct {
+	mu        sync.Mutex
+	opts      Options
+	entries   map[string]*list.Element
+	lru       *list.List // *entry, от недавно запрошенных к давним
+	bytes     int64
+	lastSweep time.Time
+}
+
+// New возвращает middleware, которое сохраняет ответы на запросы с заголовком Idempotency-Key на время opts.TTL.
+// Повтор с тем же ключом и тем же телом получает сохраненный ответ, с тем же ключом и другим телом - 422.
+// Пока первый запрос выполняется, повтор получает 409. Ответы 5xx и ответы на прерванные клиентом запросы (499)
+// не сохраняются, чтобы запрос можно было повторить.
+// Ключ действует в пределах метода и пути запроса, а при включенной аутентификации - и клиента.
+// Ответы хранятся в памяти процесса: повтор, попавший на другую реплику сервиса, выполнится еще раз.
+// При переполнении хранилища вытесняются давно не запрошенные ответы.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/idempotency"),
+		)
+
+		log.Info("idempotency middleware enabled",
+			slog.String("ttl", opts.TTL.String()),
+			slog.Int("max_entries", opts.MaxEntries),
+			slog.Int64("max_bytes", opts.MaxBytes),
+		)
+
+		s := &store{
+			opts:    opts,
+			entries: make(map[string]*list.Element),
+			lru:     list.New(),
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderKey)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqLog := log.With(
+				slog.String("idempotency_key", key),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				tracing.LogAttr(r.Context()),
+			)
+
+			if len(key) > maxKeyLength {
+				reqLog.Info("Idempotency key is too long")
+				response.WriteError(w, r, response.BadRequest, "idempotency key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+				reqLog.Info("Request body is too large", slog.Int64("limit", maxErr.Limit))
+				response.WriteError(w, r, response.BadRequest, "request body is too large")
+				return
+			}
+			if err != nil {
+				reqLog.Error("Failed to read request body", slog.String("error", err.Error()))
+				response.WriteError(w, r, response.BadRequest, "invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := r.Method + " " + r.URL.Path + " " + key
+			if id, ok := auth.FromContext(r.Context()); ok {
+				scope = id.Subject() + " " + scope
+			}
+			hash := sha256.Sum256(body)
+
+			saved, ok := s.begin(scope, hash)
+			if ok {
+				switch {
+				case saved.hash != hash:
+					reqLog.Info("Idempotency key reused with different request")
+					response.WriteError(w, r, response.IdempotencyMismatch, "idempotency key was already used with a different request")
+				case !saved.done:
+					reqLog.Info("Request with idempotency key is in progress")
+					response.WriteError(w, r, response.IdempotencyInFlight, "request with this idempotency key is in progress")
+				default:
+					reqLog.Info("Replaying saved response", slog.Int("status", saved.status))
+					for k, v := range saved.header {
+						w.Header()[k] = v
+					}
+					w.Header().Set(HeaderReplayed, "true")
+					w.WriteHeader(saved.status)
+					w.Write(saved.body)
+				}
+				return
+			}
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+
+			defer func() {
+				if p := recover(); p != nil {
+					s.abort(scope)
+					panic(p)
+				}
+
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				if status == response.StatusClientClosedRequest || r.Context().Err() != nil {
+					reqLog.Info("Request was canceled, releasing idempotency key", slog.Int("status", status))
+					s.abort(scope)
+					return
+				}
+				s.finish(scope, status, w.Header(), buf.Bytes())
+			}()
+
+			next.ServeHTTP(ww, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// begin возвращает копию сохраненной записи для scope, если она есть.
+// Иначе резервирует scope за текущим запросом и возвращает false.
+func (s *store) begin(scope string, hash [sha256.Size]byte) (entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if el, ok := s.entries[scope]; ok {
+		e := el.Value.(*entry)
+		if now.Before(e.expires) {
+			s.lru.MoveToFront(el)
+			return *e, true
+		}
+		s.remove(el)
+	}
+
+	s.entries[scope] = s.lru.PushFront(&entry{scope: scope, hash: hash, expires: now.Add(s.opts.TTL)})
+	s.evict()
+	return entry{}, false
+}
+
+// finish сохраняет ответ на запрос. Ответы 5xx не сохраняются.
+func (s *store) finish(scope string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[scope]
+	if !ok {
+		return
+	}
+
+	if status >= http.StatusInternalServerError {
+		s.remove(el)
+		return
+	}
+
+	e := el.Value.(*entry)
+	e.done = true
+	e.status = status
+	e.header = header.Clone()
+	e.body = bytes.Clone(body)
+	e.expires = time.Now().Add(s.opts.TTL)
+	s.bytes += int64(len(e.body))
+	s.lru.MoveToFront(el)
+	s.evict()
+}
+
+// abort снимает резерв scope, если запрос завершился паникой или был прерван.
+func (s *store) abort(scope string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[scope]; ok {
+		s.remove(el)
+	}
+}
+
+// evict вытесняет давно не запрошенные завершенные записи, пока хранилище превышает лимиты.
+// Резервы выполняющихся запросов не вытесняются.
+func (s *store) evict() {
+	over := func() bool {
+		return (s.opts.MaxEntries > 0 && len(s.entries) > s.opts.MaxEntries) ||
+			(s.opts.MaxBytes > 0 && s.bytes > s.opts.MaxBytes)
+	}
+
+	for el := s.lru.Back(); el != nil && over(); {
+		prev := el.Prev()
+		if el.Value.(*entry).done {
+			s.remove(el)
+		}
+		el = prev
+	}
+}
+
+// remove удаляет запись из хранилища.
+func (s *store) remove(el *list.Element) {
+	e := s.lru.Remove(el).(*entry)
+	delete(s.entries, e.scope)
+	s.bytes -= int64(len(e.body))
+}
+
+// sweep удаляет истекшие записи не чаще одного раза в минуту.
+func (s *store) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for el := s.lru.Front(); el != nil; {
+		next := el.Next()
+		if !now.Before(el.Value.(*entry).expires) {
+			s.remove(el)
+		}
+		el = next
+	}
+}
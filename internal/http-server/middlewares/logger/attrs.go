@@ -0,0 +1,16 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type attrsKey struct{}
+
+// AddAttrs добавляет атрибуты к записи "request completed" текущего запроса.
+// Используется middleware, которые подключены после logger и узнают о запросе больше, например о клиенте.
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	if extra, ok := ctx.Value(attrsKey{}).(*[]slog.Attr); ok {
+		*extra = append(*extra, attrs...)
+	}
+}
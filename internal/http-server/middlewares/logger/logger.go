@@ -1,11 +1,13 @@
 package logger
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	"gotest_23.07.25/internal/tracing"
 )
 
 func New(log *slog.Logger) func(next http.Handler) http.Handler {
@@ -23,17 +25,21 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 				slog.String("remote_addr", r.RemoteAddr),
 				slog.String("user_agent", r.UserAgent()),
 				slog.String("request_id", middleware.GetReqID(r.Context())),
+				tracing.LogAttr(r.Context()),
 			)
 
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
+			var extra []slog.Attr
+			r = r.WithContext(context.WithValue(r.Context(), attrsKey{}, &extra))
+
 			t1 := time.Now()
 			defer func() {
-				entry.Info("request completed",
+				entry.LogAttrs(r.Context(), slog.LevelInfo, "request completed", append([]slog.Attr{
 					slog.Int("status", ww.Status()),
 					slog.Int("bytes", ww.BytesWritten()),
 					slog.String("duration", time.Since(t1).String()),
-				)
+				}, extra...)...)
 			}()
 
 			next.ServeHTTP(ww, r)
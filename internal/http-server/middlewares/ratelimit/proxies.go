@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Proxies - доверенные прокси перед сервером.
+type Proxies struct {
+	prefixes []netip.Prefix
+}
+
+// ParseProxies разбирает список адресов и подсетей (CIDR) доверенных прокси.
+func ParseProxies(list []string) (*Proxies, error) {
+	const op = "http-server.middlewares.ratelimit.ParseProxies"
+
+	p := &Proxies{}
+	for _, v := range list {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid proxy address %q: %w", op, v, err)
+			}
+			p.prefixes = append(p.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid proxy subnet %q: %w", op, v, err)
+		}
+		p.prefixes = append(p.prefixes, prefix.Masked())
+	}
+
+	return p, nil
+}
+
+// ClientIP возвращает IP клиента. Если запрос пришел от доверенного прокси, X-Forwarded-For
+// просматривается справа налево и возвращается первый адрес, не принадлежащий доверенным прокси.
+func (p *Proxies) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !p.trusted(host) {
+		return host
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// Неразборчивый адрес подставлен не нашим прокси: дальше по цепочке доверять нельзя.
+			return host
+		}
+		if !p.trusted(hop) {
+			return hop
+		}
+		host = hop
+	}
+
+	return host
+}
+
+// trusted сообщает, принадлежит ли адрес доверенному прокси.
+func (p *Proxies) trusted(host string) bool {
+	if p == nil {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gotest_23.07.25/internal/http-server/middlewares/auth"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// rate limit keys:
+const (
+	KeyByIP     = "ip"
+	KeyByAPIKey = "api_key"
+	KeyByUser   = "user"
+)
+
+// headers (draft-ietf-httpapi-ratelimit-headers):
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+)
+
+// inFlightRetryAfter - Retry-After в секундах для запросов, отклоненных по лимиту одновременных запросов.
+const inFlightRetryAfter = "1"
+
+// Options - лимиты группы маршрутов.
+type Options struct {
+	// Rate - число запросов в секунду на одного клиента; 0 - без ограничения частоты.
+	Rate float64
+	// Burst - емкость корзины: сколько запросов клиент может сделать подряд.
+	Burst int
+	// KeyBy определяет клиента: ip, api_key или user. Запросы без ключа API или JWT считаются по IP.
+	KeyBy string
+	// MaxInFlight - число одновременно выполняемых запросов группы от всех клиентов; 0 - без ограничения.
+	MaxInFlight int
+	// Proxies - доверенные прокси, чей X-Forwarded-For учитывается при определении IP клиента.
+	Proxies *Proxies
+}
+
+// Validate проверяет настройки группы.
+func (o Options) Validate() error {
+	switch o.KeyBy {
+	case KeyByIP, KeyByAPIKey, KeyByUser:
+	default:
+		return fmt.Errorf("unknown key_by %q, expected one of: %s, %s, %s", o.KeyBy, KeyByIP, KeyByAPIKey, KeyByUser)
+	}
+	if o.Rate < 0 || o.MaxInFlight < 0 {
+		return fmt.Errorf("rate and max_in_flight must not be negative")
+	}
+	if o.Rate > 0 && o.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1")
+	}
+	return nil
+}
+
+// bucket - корзина токенов одного клиента.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter хранит корзины клиентов в памяти процесса.
+type limiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// Limiter - лимиты одной группы маршрутов: корзины токенов клиентов и счетчик одновременных запросов.
+// Один Limiter разделяют HTTP- и gRPC-сервер, чтобы лимиты группы действовали на оба API сразу.
+type Limiter struct {
+	opts     Options
+	buckets  *limiter
+	inFlight chan struct{}
+}
+
+// NewLimiter создает лимиты группы с настройками opts.
+func NewLimiter(opts Options) *Limiter {
+	l := &Limiter{
+		opts: opts,
+		buckets: &limiter{
+			rate:    opts.Rate,
+			burst:   float64(opts.Burst),
+			buckets: make(map[string]*bucket),
+		},
+	}
+	if opts.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, opts.MaxInFlight)
+	}
+	return l
+}
+
+// Options возвращает настройки группы.
+func (l *Limiter) Options() Options {
+	return l.opts
+}
+
+// Client возвращает ключ корзины клиента: ключ API или пользователя из ctx, как задано в KeyBy, иначе ip.
+func (l *Limiter) Client(ctx context.Context, ip string) string {
+	if id, ok := auth.FromContext(ctx); ok {
+		if (l.opts.KeyBy == KeyByAPIKey && !id.Token) || (l.opts.KeyBy == KeyByUser && id.Token) {
+			return id.Subject()
+		}
+	}
+	return "ip:" + ip
+}
+
+// Take забирает токен из корзины client, если частота запросов ограничена. Возвращает число оставшихся токенов,
+// время до полного наполнения корзины и, если токенов нет, время до появления следующего.
+func (l *Limiter) Take(client string) (remaining int, reset, wait time.Duration) {
+	if l.opts.Rate <= 0 {
+		return 0, 0, 0
+	}
+	return l.buckets.take(client, time.Now())
+}
+
+// Acquire занимает место среди одновременных запросов группы. Если мест нет, возвращает false;
+// иначе release, освобождающую место.
+func (l *Limiter) Acquire() (release func(), ok bool) {
+	if l.inFlight == nil {
+		return func() {}, true
+	}
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, true
+	default:
+		return nil, false
+	}
+}
+
+// New возвращает middleware, ограничивающее частоту запросов каждого клиента корзиной токенов
+// и число одновременно выполняемых запросов группы. Отклоненные запросы получают 429 с Retry-After.
+// Ответы на запросы с ограничением частоты содержат заголовки RateLimit-Limit, RateLimit-Remaining и RateLimit-Reset.
+// group - имя группы маршрутов для логов.
+func New(log *slog.Logger, group string, l *Limiter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		opts := l.Options()
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+			slog.String("group", group),
+		)
+
+		log.Info("rate limit middleware enabled",
+			slog.Float64("rate", opts.Rate),
+			slog.Int("burst", opts.Burst),
+			slog.String("key_by", opts.KeyBy),
+			slog.Int("max_in_flight", opts.MaxInFlight),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if opts.Rate > 0 {
+				client := l.Client(r.Context(), opts.Proxies.ClientIP(r))
+				remaining, reset, wait := l.Take(client)
+
+				w.Header().Set(HeaderLimit, strconv.Itoa(opts.Burst))
+				w.Header().Set(HeaderRemaining, strconv.Itoa(remaining))
+				w.Header().Set(HeaderReset, Seconds(reset))
+
+				if wait > 0 {
+					log.Info("Rate limit exceeded",
+						slog.String("request_id", middleware.GetReqID(r.Context())),
+						tracing.LogAttr(r.Context()),
+						slog.String("client", client),
+					)
+					w.Header().Set("Retry-After", Seconds(wait))
+					response.WriteError(w, r, response.RateLimited, "rate limit exceeded, retry in "+Seconds(wait)+"s")
+					return
+				}
+			}
+
+			release, ok := l.Acquire()
+			if !ok {
+				log.Warn("In-flight limit exceeded",
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					tracing.LogAttr(r.Context()),
+				)
+				w.Header().Set("Retry-After", inFlightRetryAfter)
+				response.WriteError(w, r, response.TooManyInFlight, "too many requests in progress, retry later")
+				return
+			}
+			defer release()
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// take забирает токен из корзины client. Возвращает число оставшихся токенов, время до полного
+// наполнения корзины и, если токенов нет, время до появления следующего.
+func (l *limiter) take(client string, now time.Time) (remaining int, reset, wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+	} else {
+		wait = l.duration(1 - b.tokens)
+	}
+
+	return int(b.tokens), l.duration(l.burst - b.tokens), wait
+}
+
+// duration возвращает время, за которое в корзину добавится tokens токенов.
+func (l *limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep удаляет полные корзины не чаще одного раза в минуту: они не отличаются от новых.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	full := l.duration(l.burst)
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// Seconds округляет d вверх до целых секунд.
+func Seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,95 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// ProblemContentType - тип содержимого ответа с ошибкой (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// StatusClientClosedRequest - нестандартный статус (nginx 499) для запросов, прерванных клиентом.
+const StatusClientClosedRequest = 499
+
+// ProblemType - вид ошибки API. Type не меняется между версиями, клиенты могут ветвиться по нему.
+type ProblemType struct {
+	Type   string
+	Title  string
+	Status int
+}
+
+// problem types:
+var (
+	BadRequest           = ProblemType{"/problems/bad-request", "Bad request", http.StatusBadRequest}
+	ValidationFailed     = ProblemType{"/problems/validation-failed", "Request validation failed", http.StatusBadRequest}
+	Unauthorized         = ProblemType{"/problems/unauthorized", "Authentication required", http.StatusUnauthorized}
+	Forbidden            = ProblemType{"/problems/forbidden", "Access denied", http.StatusForbidden}
+	NotFound             = ProblemType{"/problems/not-found", "Resource not found", http.StatusNotFound}
+	MethodNotAllowed     = ProblemType{"/problems/method-not-allowed", "Method not allowed", http.StatusMethodNotAllowed}
+	Conflict             = ProblemType{"/problems/conflict", "Request conflicts with the current state of the resource", http.StatusConflict}
+	PeriodOverlap        = ProblemType{"/problems/period-overlap", "Subscription period overlaps an existing one", http.StatusConflict}
+	PreconditionFailed   = ProblemType{"/problems/precondition-failed", "Record version does not match If-Match", http.StatusPreconditionFailed}
+	UnsupportedMediaType = ProblemType{"/problems/unsupported-media-type", "Unsupported media type", http.StatusUnsupportedMediaType}
+	IdempotencyMismatch  = ProblemType{"/problems/idempotency-key-mismatch", "Idempotency key reused with a different request", http.StatusUnprocessableEntity}
+	IdempotencyInFlight  = ProblemType{"/problems/idempotency-key-in-progress", "Request with this idempotency key is in progress", http.StatusConflict}
+	RateLimited          = ProblemType{"/problems/rate-limited", "Too many requests", http.StatusTooManyRequests}
+	TooManyInFlight      = ProblemType{"/problems/too-many-in-flight", "Too many requests in progress", http.StatusTooManyRequests}
+	ClientClosedRequest  = ProblemType{"/problems/client-closed-request", "Client closed request", StatusClientClosedRequest}
+	Internal             = ProblemType{"/problems/internal", "Internal server error", http.StatusInternalServerError}
+	Unavailable          = ProblemType{"/problems/unavailable", "Service temporarily unavailable", http.StatusServiceUnavailable}
+)
+
+// Problem - тело ответа с ошибкой в формате RFC 7807.
+// Errors и Existing - члены-расширения для ошибок валидации и пересечения периодов.
+type Problem struct {
+	Type     string                  `json:"type" example:"/problems/validation-failed"`
+	Title    string                  `json:"title" example:"Request validation failed"`
+	Status   int                     `json:"status" example:"400"`
+	Detail   string                  `json:"detail,omitempty" example:"price must be greater than zero"`
+	Instance string                  `json:"instance,omitempty" example:"host/abcdef-000001"`
+	Errors   []validation.FieldError `json:"errors,omitempty"`
+	Existing *postgre.RequestFields  `json:"existing,omitempty"`
+}
+
+// NewProblem возвращает ошибку вида t с пояснением detail.
+func NewProblem(t ProblemType, detail string) Problem {
+	return Problem{
+		Type:   t.Type,
+		Title:  t.Title,
+		Status: t.Status,
+		Detail: detail,
+	}
+}
+
+// ValidationProblem возвращает ошибку валидации со списком некорректных полей.
+func ValidationProblem(errs validation.Errors) Problem {
+	p := NewProblem(ValidationFailed, errs.Error())
+	p.Errors = errs
+	return p
+}
+
+// ConflictProblem возвращает ошибку пересечения периодов; existing - период, с которым пересекается запрос, может быть nil.
+func ConflictProblem(detail string, existing *postgre.RequestFields) Problem {
+	p := NewProblem(PeriodOverlap, detail)
+	p.Existing = existing
+	return p
+}
+
+// WriteProblem отправляет ошибку клиенту. instance - ID запроса, по которому ошибку можно найти в логах.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	p.Instance = middleware.GetReqID(r.Context())
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// WriteError отправляет ошибку вида t с пояснением detail.
+func WriteError(w http.ResponseWriter, r *http.Request, t ProblemType, detail string) {
+	WriteProblem(w, r, NewProblem(t, detail))
+}
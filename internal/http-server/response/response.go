@@ -4,7 +4,6 @@ import "gotest_23.07.25/internal/postgre"
 
 type Response struct {
 	Status       string                      `json:"status"`
-	Error        string                      `json:"error,omitempty"`
 	Message      string                      `json:"message,omitempty"`
 	Fields       postgre.RequestFields       `json:"fields,omitempty"`
 	FieldsUpdate postgre.RequestUpdateFields `json:"fieldsUpd,omitempty"`
@@ -17,10 +16,3 @@ func OK(msg string, rb *postgre.RequestFields) Response {
 		Fields:  *rb,
 	}
 }
-
-func Error(msg string) Response {
-	return Response{
-		Status: "error",
-		Error:  msg,
-	}
-}
@@ -0,0 +1,69 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// scopes:
+const (
+	ScopeSubscriptionsRead  = "subscriptions:read"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeReportsRead        = "reports:read"
+	ScopeAdmin              = "admin"
+)
+
+// Scopes - все известные области доступа.
+var Scopes = []string{ScopeSubscriptionsRead, ScopeSubscriptionsWrite, ScopeReportsRead, ScopeAdmin}
+
+const (
+	// keyPrefix отличает ключи API от других секретов, например в логах или при сканировании репозиториев.
+	keyPrefix = "subs_"
+	// keyBytes - число случайных байт в ключе.
+	keyBytes = 32
+	// PrefixLength - длина начала ключа, которое хранится открыто, чтобы ключ можно было узнать в списке.
+	PrefixLength = len(keyPrefix) + 8
+)
+
+// Generate возвращает новый ключ и его открытое начало.
+func Generate() (key, prefix string, err error) {
+	const op = "internal.lib.apikey.Generate"
+
+	buf := make([]byte, keyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, key[:PrefixLength], nil
+}
+
+// Hash возвращает хеш ключа, под которым он хранится в БД.
+// Ключ - 32 случайных байта, поэтому медленный хеш для паролей не нужен.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidScope сообщает, известна ли область доступа.
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope сообщает, разрешена ли область scope набором scopes. Ключ с областью admin имеет доступ ко всему.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package billing
+
+import "time"
+
+// BilledMonths возвращает количество оплачиваемых месяцев подписки в периоде [from, to].
+//
+// Цена подписки считается ежемесячной. Месяц оплачивается целиком, если подписка
+// активна в пределах периода хотя бы один день этого месяца: неполные месяцы в начале
+// и в конце пересечения округляются вверх до полного месяца. Подписка без end_date
+// считается активной до конца периода. Если подписка не пересекается с периодом, возвращается 0.
+func BilledMonths(startDate time.Time, endDate *time.Time, from, to time.Time) uint64 {
+	first := startDate
+	if first.Before(from) {
+		first = from
+	}
+
+	last := to
+	if endDate != nil && endDate.Before(to) {
+		last = *endDate
+	}
+
+	if last.Before(first) {
+		return 0
+	}
+
+	return uint64(monthIndex(last) - monthIndex(first) + 1)
+}
+
+// monthIndex возвращает порядковый номер календарного месяца даты.
+func monthIndex(t time.Time) int {
+	return t.Year()*12 + int(t.Month()) - 1
+}
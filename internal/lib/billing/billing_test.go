@@ -0,0 +1,50 @@
+package billing
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func datePtr(s string) *time.Time {
+	t := date(s)
+	return &t
+}
+
+func TestBilledMonths(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    string
+		end      *time.Time
+		from, to string
+		want     uint64
+	}{
+		{"whole months", "2025-01-01", datePtr("2025-03-31"), "2025-01-01", "2025-12-31", 3},
+		{"partial first and last month", "2025-01-20", datePtr("2025-03-05"), "2025-01-01", "2025-12-31", 3},
+		{"period cuts partial months", "2024-01-01", nil, "2025-01-15", "2025-03-10", 3},
+		{"no end date runs to period end", "2025-02-10", nil, "2025-01-01", "2025-06-30", 5},
+		{"end before start", "2025-03-01", datePtr("2025-02-01"), "2025-01-01", "2025-12-31", 0},
+		{"single day", "2025-04-15", datePtr("2025-04-15"), "2025-01-01", "2025-12-31", 1},
+		{"single day on period edge", "2025-03-10", datePtr("2025-03-10"), "2025-01-01", "2025-03-10", 1},
+		{"year boundary", "2024-11-20", datePtr("2025-02-10"), "2024-01-01", "2025-12-31", 4},
+		{"ends before period", "2024-01-01", datePtr("2024-12-31"), "2025-01-01", "2025-12-31", 0},
+		{"starts after period", "2026-01-01", nil, "2025-01-01", "2025-12-31", 0},
+		{"ends on period start", "2024-06-01", datePtr("2025-01-01"), "2025-01-01", "2025-12-31", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BilledMonths(date(tt.start), tt.end, date(tt.from), date(tt.to))
+			if got != tt.want {
+				t.Errorf("BilledMonths() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
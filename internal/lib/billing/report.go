@@ -0,0 +1,133 @@
+package billing
+
+import (
+	"sort"
+	"time"
+
+	"gotest_23.07.25/internal/postgre"
+)
+
+// MonthLayout - формат месяца в строках отчета.
+const MonthLayout = "2006-01"
+
+// Aggregator считает расходы по подпискам за период и группирует их по postgre.ReportGroupBy.
+// Используется хранилищами, которые не умеют строить отчет средствами СУБД.
+//
+// Каждый календарный месяц оплачивается для пары (service_name, user_id) один раз: если в месяце
+// активны несколько периодов одной подписки, учитывается цена периода, начавшегося позже.
+type Aggregator struct {
+	by     postgre.ReportGroupBy
+	from   time.Time
+	to     time.Time
+	months map[monthKey]billedMonth
+}
+
+type monthKey struct {
+	serviceName string
+	userID      string
+	month       string
+}
+
+type billedMonth struct {
+	id        int64
+	price     uint16
+	startDate time.Time
+}
+
+type group struct {
+	price uint64
+	ids   map[int64]struct{}
+}
+
+func NewAggregator(by postgre.ReportGroupBy, from, to time.Time) *Aggregator {
+	return &Aggregator{
+		by:     by,
+		from:   from,
+		to:     to,
+		months: make(map[monthKey]billedMonth),
+	}
+}
+
+// Add учитывает период подписки: отмечает каждый оплачиваемый месяц периода внутри [from, to].
+func (a *Aggregator) Add(rb postgre.RequestFields) {
+	months := BilledMonths(rb.StartDate, rb.EndDate, a.from, a.to)
+	if months == 0 {
+		return
+	}
+
+	first := rb.StartDate
+	if first.Before(a.from) {
+		first = a.from
+	}
+	month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for range months {
+		key := monthKey{
+			serviceName: rb.ServiceName,
+			userID:      rb.UserId,
+			month:       month.Format(MonthLayout),
+		}
+
+		if billed, ok := a.months[key]; !ok || billed.startDate.Before(rb.StartDate) {
+			a.months[key] = billedMonth{id: rb.ID, price: rb.Price, startDate: rb.StartDate}
+		}
+
+		month = month.AddDate(0, 1, 0)
+	}
+}
+
+// Total возвращает общую стоимость учтенных подписок.
+func (a *Aggregator) Total() uint64 {
+	var total uint64
+	for _, billed := range a.months {
+		total += uint64(billed.price)
+	}
+	return total
+}
+
+// Groups возвращает строки отчета, отсортированные по сервису, пользователю и месяцу.
+func (a *Aggregator) Groups() []postgre.ReportGroup {
+	groups := make(map[monthKey]*group)
+	for key, billed := range a.months {
+		if !a.by.ServiceName {
+			key.serviceName = ""
+		}
+		if !a.by.UserID {
+			key.userID = ""
+		}
+		if !a.by.Month {
+			key.month = ""
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{ids: make(map[int64]struct{})}
+			groups[key] = g
+		}
+		g.price += uint64(billed.price)
+		g.ids[billed.id] = struct{}{}
+	}
+
+	result := make([]postgre.ReportGroup, 0, len(groups))
+	for key, g := range groups {
+		result = append(result, postgre.ReportGroup{
+			ServiceName: key.serviceName,
+			UserId:      key.userID,
+			Month:       key.month,
+			Price:       g.price,
+			Count:       uint64(len(g.ids)),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ServiceName != result[j].ServiceName {
+			return result[i].ServiceName < result[j].ServiceName
+		}
+		if result[i].UserId != result[j].UserId {
+			return result[i].UserId < result[j].UserId
+		}
+		return result[i].Month < result[j].Month
+	})
+
+	return result
+}
@@ -0,0 +1,111 @@
+package billing
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"gotest_23.07.25/internal/postgre"
+)
+
+// reportFixture - подписки и ожидаемый отчет из testdata. Те же файлы проверяют хранилища,
+// считающие отчет средствами СУБД.
+type reportFixture struct {
+	From          time.Time               `json:"from"`
+	To            time.Time               `json:"to"`
+	Subscriptions []postgre.RequestFields `json:"subscriptions"`
+	Total         uint64                  `json:"total"`
+	// Count - число периодов в отчете без группировки.
+	Count     uint64                `json:"count"`
+	ByService []postgre.ReportGroup `json:"by_service"`
+	ByMonth   []postgre.ReportGroup `json:"by_month"`
+}
+
+// reportFixtures - файлы фикстур: обычный отчет и два периода одной подписки в одном месяце.
+var reportFixtures = []string{"testdata/report.json", "testdata/same_month.json"}
+
+func loadReportFixture(t *testing.T, path string) reportFixture {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var f reportFixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	for i := range f.Subscriptions {
+		f.Subscriptions[i].ID = int64(i + 1)
+	}
+	return f
+}
+
+func aggregate(f reportFixture, by postgre.ReportGroupBy) *Aggregator {
+	a := NewAggregator(by, f.From, f.To)
+	for _, rb := range f.Subscriptions {
+		a.Add(rb)
+	}
+	return a
+}
+
+func TestAggregatorTotal(t *testing.T) {
+	for _, path := range reportFixtures {
+		t.Run(path, func(t *testing.T) {
+			f := loadReportFixture(t, path)
+
+			if got := aggregate(f, postgre.ReportGroupBy{}).Total(); got != f.Total {
+				t.Errorf("Total() = %d, want %d", got, f.Total)
+			}
+		})
+	}
+}
+
+func TestAggregatorGroups(t *testing.T) {
+	for _, path := range reportFixtures {
+		f := loadReportFixture(t, path)
+
+		tests := []struct {
+			name string
+			by   postgre.ReportGroupBy
+			want []postgre.ReportGroup
+		}{
+			{"by service", postgre.ReportGroupBy{ServiceName: true}, f.ByService},
+			{"by month", postgre.ReportGroupBy{Month: true}, f.ByMonth},
+			{"no grouping", postgre.ReportGroupBy{}, []postgre.ReportGroup{{Price: f.Total, Count: f.Count}}},
+		}
+
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				got := aggregate(f, tt.by).Groups()
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Groups() = %+v, want %+v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// TestAggregatorSameMonth закрепляет правило: если два периода одной подписки активны в одном месяце,
+// месяц оплачивается один раз по цене периода, начавшегося позже.
+func TestAggregatorSameMonth(t *testing.T) {
+	from, to := date("2025-02-01"), date("2025-02-28")
+	early := postgre.RequestFields{ID: 1, ServiceName: "Netflix", Price: 100, UserId: "u", StartDate: date("2025-01-01"), EndDate: datePtr("2025-02-10")}
+	late := postgre.RequestFields{ID: 2, ServiceName: "Netflix", Price: 300, UserId: "u", StartDate: date("2025-02-20")}
+
+	// Порядок добавления не влияет на результат.
+	for _, order := range [][]postgre.RequestFields{{early, late}, {late, early}} {
+		a := NewAggregator(postgre.ReportGroupBy{}, from, to)
+		for _, rb := range order {
+			a.Add(rb)
+		}
+
+		want := []postgre.ReportGroup{{Price: 300, Count: 1}}
+		if got := a.Groups(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Groups() = %+v, want %+v", got, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// signing algorithms:
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// ErrInvalidToken возвращается для токена, который не прошел проверку.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Options - настройки проверки токенов.
+type Options struct {
+	// Secret - ключ HS256; пустой - токены HS256 не принимаются.
+	Secret string
+	// JWKSFile - путь к файлу JWKS с открытыми ключами RS256/ES256; пустой - такие токены не принимаются.
+	JWKSFile string
+	// Issuer и Audience, если заданы, должны совпадать с iss и aud токена.
+	Issuer   string
+	Audience string
+	// UserClaim - claim с UUID пользователя.
+	UserClaim string
+	// RoleClaim - claim со списком ролей (массив строк или строка через пробел).
+	RoleClaim string
+	// AdminRole - роль, снимающая ограничение доступа подписками своего пользователя.
+	AdminRole string
+	// Leeway - допустимое расхождение часов при проверке exp и nbf.
+	Leeway time.Duration
+}
+
+// Claims - данные клиента из проверенного токена.
+type Claims struct {
+	// UserID - значение UserClaim. Для не-администратора это UUID пользователя.
+	UserID string
+	Admin  bool
+}
+
+// Verifier проверяет подпись и claims токенов.
+type Verifier struct {
+	opts   Options
+	secret []byte
+	keys   []publicKey
+	parser *jwt.Parser
+}
+
+// NewVerifier возвращает Verifier для ключей из opts. Нужен хотя бы один из Secret и JWKSFile.
+func NewVerifier(opts Options) (*Verifier, error) {
+	const op = "internal.lib.token.NewVerifier"
+
+	if opts.UserClaim == "" {
+		return nil, fmt.Errorf("%s: user claim is not set", op)
+	}
+
+	v := &Verifier{opts: opts, secret: []byte(opts.Secret)}
+
+	var methods []string
+	if opts.Secret != "" {
+		methods = append(methods, AlgHS256)
+	}
+	if opts.JWKSFile != "" {
+		keys, err := loadJWKS(opts.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		v.keys = keys
+		methods = append(methods, AlgRS256, AlgES256)
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("%s: neither secret nor jwks file is set", op)
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	v.parser = jwt.NewParser(parserOpts...)
+
+	return v, nil
+}
+
+// Verify проверяет токен и возвращает данные клиента. Ошибки проверки оборачивают ErrInvalidToken.
+func (v *Verifier) Verify(raw string) (Claims, error) {
+	var mc jwt.MapClaims
+	if _, err := v.parser.ParseWithClaims(raw, &mc, v.key); err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	var claims Claims
+
+	user, ok := mc[v.opts.UserClaim].(string)
+	if !ok || user == "" {
+		return Claims{}, fmt.Errorf("%w: claim %s is missing", ErrInvalidToken, v.opts.UserClaim)
+	}
+	claims.UserID = user
+
+	claims.Admin = v.opts.AdminRole != "" && slices.Contains(roles(mc[v.opts.RoleClaim]), v.opts.AdminRole)
+
+	if !claims.Admin {
+		id, err := uuid.Parse(user)
+		if err != nil {
+			return Claims{}, fmt.Errorf("%w: claim %s is not a UUID", ErrInvalidToken, v.opts.UserClaim)
+		}
+		claims.UserID = id.String()
+	}
+
+	return claims, nil
+}
+
+// key выбирает ключ проверки подписи по алгоритму и kid токена.
+// Если kid не задан, используется единственный подходящий ключ из JWKS.
+func (v *Verifier) key(t *jwt.Token) (any, error) {
+	alg := t.Method.Alg()
+	if alg == AlgHS256 {
+		return v.secret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+
+	var found any
+	for _, k := range v.keys {
+		if !keyFits(k, alg) || (kid != "" && k.kid != kid) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("several %s keys match kid %q", alg, kid)
+		}
+		found = k.key
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no %s key with kid %q", alg, kid)
+	}
+
+	return found, nil
+}
+
+// keyFits сообщает, подходит ли ключ для алгоритма alg.
+func keyFits(k publicKey, alg string) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+	switch key := k.key.(type) {
+	case *rsa.PublicKey:
+		return alg == AlgRS256
+	case *ecdsa.PublicKey:
+		return alg == AlgES256 && key.Curve.Params().Name == "P-256"
+	}
+	return false
+}
+
+// roles возвращает роли из claim: массива строк или строки через пробел.
+func roles(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// error codes:
+const (
+	CodeRequired       = "required"
+	CodeTooLong        = "too_long"
+	CodeInvalidChars   = "invalid_characters"
+	CodeInvalidUUID    = "invalid_uuid"
+	CodeMustBePositive = "must_be_positive"
+	CodeEndBeforeStart = "end_before_start"
+	CodeReadOnly       = "read_only"
+	CodeUnknownField   = "unknown_field"
+	CodeInvalidType    = "invalid_type"
+	CodeInvalidJSON    = "invalid_json"
+	CodeInvalidValue   = "invalid_value"
+)
+
+// MaxServiceNameLength - максимальная длина имени сервиса в символах.
+const MaxServiceNameLength = 100
+
+// serviceNameChars - допустимые символы имени сервиса: буквы, цифры, пробел и . _ - + & ' ( ) ! :
+var serviceNameChars = regexp.MustCompile(`^[\p{L}\p{N} ._\-+&'()!:]+$`)
+
+// FieldError - ошибка валидации одного поля запроса.
+// Field пустой, если ошибка относится к запросу целиком (например, неразборчивый JSON).
+type FieldError struct {
+	Field   string `json:"field" example:"price"`
+	Code    string `json:"code" example:"must_be_positive"`
+	Message string `json:"message" example:"price must be greater than zero"`
+}
+
+// Errors - список ошибок валидации. Nil означает, что запрос корректен.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *Errors) add(field, code, format string, args ...any) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// DecodeJSON строго разбирает JSON из body в v: неизвестные поля, неверные типы значений,
+// пустое тело и данные после JSON-документа возвращаются как ошибки валидации.
+func DecodeJSON(body io.Reader, v any) Errors {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return Errors{{Code: CodeInvalidJSON, Message: "request body must contain a single JSON document"}}
+	}
+
+	return nil
+}
+
+// Subscription проверяет поля новой записи о подписке. id и version назначает сервер,
+// поэтому заданные в запросе id или version - ошибка read_only.
+func Subscription(rb postgre.RequestFields) Errors {
+	var errs Errors
+
+	if rb.ID != 0 {
+		errs.add("id", CodeReadOnly, "id is assigned by the server")
+	}
+	if rb.Version != 0 {
+		errs.add("version", CodeReadOnly, "version is assigned by the server")
+	}
+
+	errs = append(errs, ServiceName("service_name", rb.ServiceName, true)...)
+	errs = append(errs, Price("price", rb.Price)...)
+	errs = append(errs, UUID("user_id", rb.UserId, true)...)
+	errs = append(errs, Period(rb.StartDate, rb.EndDate)...)
+
+	return errs
+}
+
+// Update проверяет новые поля подписки при полной замене.
+func Update(rb postgre.RequestUpdateFields) Errors {
+	var errs Errors
+
+	errs = append(errs, Price("price", rb.Price)...)
+	errs = append(errs, Period(rb.StartDate, rb.EndDate)...)
+
+	return errs
+}
+
+// ServiceName проверяет длину и символы имени сервиса. Пустое имя допустимо, если required равен false.
+func ServiceName(field, name string, required bool) Errors {
+	var errs Errors
+
+	switch {
+	case strings.TrimSpace(name) == "":
+		if required || name != "" {
+			errs.add(field, CodeRequired, "%s is required", field)
+		}
+	case utf8.RuneCountInString(name) > MaxServiceNameLength:
+		errs.add(field, CodeTooLong, "%s must be at most %d characters long", field, MaxServiceNameLength)
+	case !serviceNameChars.MatchString(name) || name != strings.TrimSpace(name):
+		errs.add(field, CodeInvalidChars, "%s may contain only letters, digits, spaces and . _ - + & ' ( ) ! : and must not start or end with a space", field)
+	}
+
+	return errs
+}
+
+// UUID проверяет, что значение - UUID в каноническом виде. Пустое значение допустимо, если required равен false.
+func UUID(field, value string, required bool) Errors {
+	var errs Errors
+
+	if value == "" {
+		if required {
+			errs.add(field, CodeRequired, "%s is required", field)
+		}
+		return errs
+	}
+
+	if _, err := uuid.Parse(value); err != nil || len(value) != 36 {
+		errs.add(field, CodeInvalidUUID, "%s must be a UUID in the form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx", field)
+	}
+
+	return errs
+}
+
+// Price проверяет, что цена больше нуля.
+func Price(field string, price uint16) Errors {
+	var errs Errors
+
+	if price == 0 {
+		errs.add(field, CodeMustBePositive, "%s must be greater than zero", field)
+	}
+
+	return errs
+}
+
+// Period проверяет даты подписки: start_date обязательна, end_date не раньше start_date.
+func Period(startDate time.Time, endDate *time.Time) Errors {
+	var errs Errors
+
+	if startDate.IsZero() {
+		errs.add("start_date", CodeRequired, "start_date is required")
+		return errs
+	}
+
+	if endDate != nil && endDate.Before(startDate) {
+		errs.add("end_date", CodeEndBeforeStart, "end_date cannot be before start_date")
+	}
+
+	return errs
+}
+
+// Range проверяет границы периода отчета: обе даты обязательны, end_date не раньше start_date.
+func Range(startDate, endDate time.Time) Errors {
+	var errs Errors
+
+	if startDate.IsZero() {
+		errs.add("start_date", CodeRequired, "start_date is required")
+	}
+	if endDate.IsZero() {
+		errs.add("end_date", CodeRequired, "end_date is required")
+	}
+	if len(errs) == 0 && endDate.Before(startDate) {
+		errs.add("end_date", CodeEndBeforeStart, "end_date cannot be before start_date")
+	}
+
+	return errs
+}
+
+// decodeError переводит ошибку encoding/json в ошибку валидации.
+func decodeError(err error) Errors {
+	var (
+		typeErr   *json.UnmarshalTypeError
+		syntaxErr *json.SyntaxError
+		timeErr   *time.ParseError
+	)
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return Errors{{Code: CodeRequired, Message: "request body is empty"}}
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return Errors{{Code: CodeInvalidType, Message: fmt.Sprintf("request body must be a JSON %s", typeErr.Type.Kind())}}
+		}
+		return Errors{{Field: typeErr.Field, Code: CodeInvalidType, Message: fmt.Sprintf("%s must be a %s", typeErr.Field, typeName(typeErr))}}
+	case errors.As(err, &timeErr):
+		return Errors{{Code: CodeInvalidValue, Message: fmt.Sprintf("invalid date %s: dates must be in RFC 3339 format", timeErr.Value)}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return Errors{{Code: CodeInvalidJSON, Message: "request body is not valid JSON"}}
+	}
+
+	// encoding/json не экспортирует тип ошибки неизвестного поля: json: unknown field "name"
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return Errors{{Field: field, Code: CodeUnknownField, Message: fmt.Sprintf("unknown field %s", field)}}
+	}
+
+	return Errors{{Code: CodeInvalidJSON, Message: err.Error()}}
+}
+
+func typeName(err *json.UnmarshalTypeError) string {
+	switch err.Type.Kind().String() {
+	case "uint8", "uint16", "uint32", "uint64", "int", "int8", "int16", "int32", "int64":
+		return "whole number in range"
+	case "struct":
+		if err.Type.String() == "time.Time" {
+			return "date string"
+		}
+	}
+	return err.Type.String()
+}
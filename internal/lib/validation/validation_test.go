@@ -0,0 +1,63 @@
+package validation_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest_23.07.25/internal/lib/validation"
+	"gotest_23.07.25/internal/postgre"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"single document", `{"price": 100}`, ""},
+		{"trailing whitespace", "{\"price\": 100}\n\t ", ""},
+		{"second document", `{"price": 100} {"price": 200}`, validation.CodeInvalidJSON},
+		{"trailing garbage", `{"price": 100} garbage`, validation.CodeInvalidJSON},
+		{"trailing bracket", `{"price": 100}]`, validation.CodeInvalidJSON},
+		{"empty body", ``, validation.CodeRequired},
+		{"unknown field", `{"cost": 100}`, validation.CodeUnknownField},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rb postgre.RequestUpdateFields
+			errs := validation.DecodeJSON(strings.NewReader(tt.body), &rb)
+
+			switch {
+			case tt.code == "" && errs != nil:
+				t.Errorf("DecodeJSON(%q) = %v, want no errors", tt.body, errs)
+			case tt.code != "" && (len(errs) != 1 || errs[0].Code != tt.code):
+				t.Errorf("DecodeJSON(%q) = %+v, want code %s", tt.body, errs, tt.code)
+			}
+		})
+	}
+}
+
+func TestSubscriptionReadOnly(t *testing.T) {
+	rb := postgre.RequestFields{
+		ID:          7,
+		ServiceName: "Netflix",
+		Price:       100,
+		UserId:      "11111111-1111-1111-1111-111111111111",
+		StartDate:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Version:     3,
+	}
+
+	errs := validation.Subscription(rb)
+	if len(errs) != 2 ||
+		errs[0].Field != "id" || errs[0].Code != validation.CodeReadOnly ||
+		errs[1].Field != "version" || errs[1].Code != validation.CodeReadOnly {
+		t.Errorf("Subscription() = %+v, want read_only for id and version", errs)
+	}
+
+	rb.ID, rb.Version = 0, 0
+	if errs := validation.Subscription(rb); errs != nil {
+		t.Errorf("Subscription() = %+v, want no errors", errs)
+	}
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"gotest_23.07.25/internal/postgre"
+)
+
+// apiKey - ключ доступа вместе с хешем, по которому его ищет APIKeyByHash.
+type apiKey struct {
+	postgre.APIKey
+	hash string
+}
+
+// CreateAPIKey сохраняет ключ с хешем hash. Семантика совпадает с postgre.Storage.CreateAPIKey.
+func (s *Storage) CreateAPIKey(ctx context.Context, key postgre.APIKey, hash string) (*postgre.APIKey, error) {
+	const op = "internal.memory.CreateAPIKey"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.hash == hash {
+			return nil, fmt.Errorf("%s: %w", op, &postgre.Error{Kind: postgre.ErrConflict, Reason: "api key already exists"})
+		}
+	}
+
+	key.ID = int64(len(s.keys) + 1)
+	key.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	key.Scopes = slices.Clone(key.Scopes)
+	s.keys = append(s.keys, apiKey{APIKey: key, hash: hash})
+
+	return &key, nil
+}
+
+// APIKeyByHash возвращает действующий (не отозванный) ключ по хешу или postgre.ErrRecordNotFound.
+func (s *Storage) APIKeyByHash(ctx context.Context, hash string) (*postgre.APIKey, error) {
+	const op = "internal.memory.APIKeyByHash"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.hash == hash && k.RevokedAt == nil {
+			key := k.APIKey
+			return &key, nil
+		}
+	}
+
+	return nil, postgre.ErrRecordNotFound
+}
+
+// ListAPIKeys возвращает все ключи, включая отозванные, в порядке создания.
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]postgre.APIKey, error) {
+	const op = "internal.memory.ListAPIKeys"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]postgre.APIKey, len(s.keys))
+	for i, k := range s.keys {
+		keys[i] = k.APIKey
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ. Для отсутствующего или уже отозванного ключа возвращает postgre.ErrRecordNotFound.
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "internal.memory.RevokeAPIKey"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.keys {
+		if s.keys[i].ID == id && s.keys[i].RevokedAt == nil {
+			now := time.Now().UTC().Truncate(time.Second)
+			s.keys[i].RevokedAt = &now
+			return nil
+		}
+	}
+
+	return postgre.ErrRecordNotFound
+}
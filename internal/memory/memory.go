@@ -0,0 +1,578 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gotest_23.07.25/internal/lib/billing"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// errInvalidPrice повторяет ограничение CHECK (price > 0) таблицы subscriptions.
+var errInvalidPrice = &postgre.Error{Kind: postgre.ErrInvalidInput, Reason: "price must be greater than zero"}
+
+// Storage хранит подписки в памяти процесса.
+// Повторяет семантику postgre.Storage: периоды одной пары (service_name, user_id) не пересекаются
+// (postgre.OverlapError), для отсутствующих записей возвращается postgre.ErrRecordNotFound.
+type Storage struct {
+	mu      sync.RWMutex
+	lastID  int64
+	records []record
+	keys    []apiKey
+}
+
+type record struct {
+	id      int64
+	version int64
+	fields  postgre.RequestFields
+}
+
+func New() *Storage {
+	return &Storage{}
+}
+
+// Create создает новую запись о подписке.
+// Возвращает id новой записи.
+func (s *Storage) Create(ctx context.Context, rb postgre.RequestFields) (int64, error) {
+	const op = "internal.memory.Create"
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.insert(rb)
+	if err != nil {
+		if errors.Is(err, postgre.ErrSubscriptionExists) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("Create done successfully", slog.String("op", op), slog.Int64("id", id))
+	return id, nil
+}
+
+// CreateBatch создает записи о подписках и возвращает результат по каждой записи.
+// Семантика совпадает с postgre.Storage.CreateBatch: при atomic и хотя бы одной несозданной записи
+// пакет не сохраняется.
+func (s *Storage) CreateBatch(ctx context.Context, rows []postgre.RequestFields, atomic bool) ([]postgre.BatchResult, error) {
+	const op = "internal.memory.CreateBatch"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastID, count := s.lastID, len(s.records)
+
+	results := make([]postgre.BatchResult, len(rows))
+	failed := false
+
+	for i, rb := range rows {
+		id, err := s.insert(rb)
+		if err != nil {
+			if !postgre.IsRowError(err) {
+				s.lastID, s.records = lastID, s.records[:count]
+				return nil, fmt.Errorf("%s: row %d: %w", op, i+1, err)
+			}
+			results[i].Err = err
+			failed = true
+			continue
+		}
+		results[i].ID = id
+	}
+
+	if atomic && failed {
+		s.lastID, s.records = lastID, s.records[:count]
+		for i := range results {
+			results[i].ID = 0
+		}
+		slog.Info("Create batch rolled back", slog.String("op", op))
+		return results, nil
+	}
+
+	slog.Info("Create batch done successfully", slog.String("op", op))
+	return results, nil
+}
+
+// Read возвращает информацию о подписке по ключу: id или имени сервиса и ID пользователя.
+func (s *Storage) Read(ctx context.Context, key postgre.SubscriptionKey) (*postgre.RequestFields, error) {
+	const op = "internal.memory.Read"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.find(key)
+	if i < 0 {
+		return nil, postgre.ErrRecordNotFound
+	}
+
+	rb := withID(s.records[i])
+
+	slog.Info("Read done successfully", slog.String("op", op))
+	return &rb, nil
+}
+
+// Update обновляет информацию о подписке и возвращает новую версию записи.
+// Если version не 0, запись обновляется только при совпадении версии, иначе возвращается postgre.ErrVersionMismatch.
+func (s *Storage) Update(ctx context.Context, key postgre.SubscriptionKey, rb postgre.RequestUpdateFields, version int64) (int64, error) {
+	const op = "internal.memory.Update"
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rb.Price == 0 {
+		return 0, fmt.Errorf("%s: %w", op, errInvalidPrice)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.find(key)
+	if i < 0 {
+		return 0, postgre.ErrRecordNotFound
+	}
+
+	if version != 0 && version != s.records[i].version {
+		return 0, postgre.ErrVersionMismatch
+	}
+
+	fields := s.records[i].fields
+	fields.Price = rb.Price
+	fields.StartDate = truncateDate(rb.StartDate)
+	fields.EndDate = truncateDatePtr(rb.EndDate)
+	if fields.EndDate != nil && fields.EndDate.Before(fields.StartDate) {
+		return 0, fmt.Errorf("%s: %w", op, postgre.ErrInvalidPeriod)
+	}
+
+	if j := s.findOverlap(fields, s.records[i].id); j >= 0 {
+		return 0, &postgre.OverlapError{Existing: withID(s.records[j])}
+	}
+
+	s.records[i].fields = fields
+	s.records[i].version++
+
+	slog.Info("Update done successfully", slog.String("op", op))
+	return s.records[i].version, nil
+}
+
+// Patch частично изменяет подписку (см. postgre.PatchFields) и возвращает ее полную запись после изменения.
+// Версия проверяется так же, как в Update.
+func (s *Storage) Patch(ctx context.Context, key postgre.SubscriptionKey, patch postgre.PatchFields, version int64) (*postgre.RequestFields, error) {
+	const op = "internal.memory.Patch"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if patch.Price != nil && *patch.Price == 0 {
+		return nil, fmt.Errorf("%s: %w", op, errInvalidPrice)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.find(key)
+	if i < 0 {
+		return nil, postgre.ErrRecordNotFound
+	}
+
+	if version != 0 && version != s.records[i].version {
+		return nil, postgre.ErrVersionMismatch
+	}
+
+	fields := normalize(patch.Apply(clone(s.records[i].fields)))
+	if fields.EndDate != nil && fields.EndDate.Before(fields.StartDate) {
+		return nil, postgre.ErrInvalidPeriod
+	}
+
+	if j := s.findOverlap(fields, s.records[i].id); j >= 0 {
+		return nil, &postgre.OverlapError{Existing: withID(s.records[j])}
+	}
+
+	s.records[i].fields = fields
+	s.records[i].version++
+	rb := withID(s.records[i])
+
+	slog.Info("Patch done successfully", slog.String("op", op))
+	return &rb, nil
+}
+
+// Delete удаляет запись о подписке.
+// Версия проверяется так же, как в Update.
+func (s *Storage) Delete(ctx context.Context, key postgre.SubscriptionKey, version int64) error {
+	const op = "internal.memory.Delete"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.find(key)
+	if i < 0 {
+		return postgre.ErrRecordNotFound
+	}
+
+	if version != 0 && version != s.records[i].version {
+		return postgre.ErrVersionMismatch
+	}
+
+	s.records = append(s.records[:i], s.records[i+1:]...)
+
+	slog.Info("Delete done successfully", slog.String("op", op))
+	return nil
+}
+
+// List возвращает страницу списка подписок с фильтрами, сортировкой и курсором из params.
+func (s *Storage) List(ctx context.Context, params postgre.ListParams) (*postgre.ListPage, error) {
+	const op = "internal.memory.List"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var cursor *record
+	if params.Cursor != nil {
+		rec, err := cursorRecord(params.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		cursor = &rec
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := s.matched(params)
+
+	page := &postgre.ListPage{
+		Subscriptions: []postgre.RequestFields{},
+		Total:         uint64(len(matched)),
+	}
+
+	limit := params.PageSize()
+	for _, rec := range matched {
+		if cursor != nil && compare(rec, *cursor, params) <= 0 {
+			continue
+		}
+		if len(page.Subscriptions) == limit {
+			page.NextCursor = params.NextCursor(page.Subscriptions[limit-1])
+			break
+		}
+		page.Subscriptions = append(page.Subscriptions, withID(rec))
+	}
+
+	slog.Info("List done successfully", slog.String("op", op))
+	return page, nil
+}
+
+// Export передает в fn подписки под фильтрами, сортировкой и курсором params.
+// Записи выбираются под блокировкой, а fn вызывается уже без нее.
+func (s *Storage) Export(ctx context.Context, params postgre.ListParams, fn func(postgre.RequestFields) error) error {
+	const op = "internal.memory.Export"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var cursor *record
+	if params.Cursor != nil {
+		rec, err := cursorRecord(params.Cursor)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		cursor = &rec
+	}
+
+	s.mu.RLock()
+	matched := s.matched(params)
+	rows := make([]postgre.RequestFields, 0, len(matched))
+	for _, rec := range matched {
+		if cursor != nil && compare(rec, *cursor, params) <= 0 {
+			continue
+		}
+		rows = append(rows, withID(rec))
+	}
+	s.mu.RUnlock()
+
+	for _, rb := range rows {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := fn(rb); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	slog.Info("Export done successfully", slog.String("op", op), slog.Int("count", len(rows)))
+	return nil
+}
+
+// RangePrice возвращает общую стоимость подписок за указанный диапазон дат и по указанным имени сервиса и id пользователя.
+// Цена умножается на число оплачиваемых месяцев, см. billing.BilledMonths и billing.Aggregator.
+func (s *Storage) RangePrice(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string) (uint64, error) {
+	const op = "internal.memory.RangePrice"
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	aggregator := s.aggregate(start_date, end_date, service_name, user_id, postgre.ReportGroupBy{})
+
+	slog.Info("Range price done successfully", slog.String("op", op))
+	return aggregator.Total(), nil
+}
+
+// Report возвращает расходы на подписки за период, сгруппированные по полям groupBy.
+// Подписки отбираются тем же фильтром, что и в RangePrice.
+func (s *Storage) Report(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy postgre.ReportGroupBy) ([]postgre.ReportGroup, error) {
+	const op = "internal.memory.Report"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	aggregator := s.aggregate(start_date, end_date, service_name, user_id, groupBy)
+
+	slog.Info("Report done successfully", slog.String("op", op))
+	return aggregator.Groups(), nil
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+// insert добавляет запись о подписке и возвращает ее id. Вызывается под s.mu.
+// Если период пересекается с существующим, возвращает postgre.OverlapError.
+func (s *Storage) insert(rb postgre.RequestFields) (int64, error) {
+	if rb.Price == 0 {
+		return 0, errInvalidPrice
+	}
+
+	rb = normalize(rb)
+	if rb.EndDate != nil && rb.EndDate.Before(rb.StartDate) {
+		return 0, postgre.ErrInvalidPeriod
+	}
+
+	if i := s.findOverlap(rb, 0); i >= 0 {
+		slog.Info("Subsctibtion period overlaps", slog.String("service_name", rb.ServiceName), slog.String("user_id", rb.UserId), slog.Int64("existing_id", s.records[i].id))
+		return 0, &postgre.OverlapError{Existing: withID(s.records[i])}
+	}
+
+	s.lastID++
+	rb.ID, rb.Version = 0, 0
+	s.records = append(s.records, record{id: s.lastID, version: 1, fields: rb})
+
+	return s.lastID, nil
+}
+
+// matched возвращает записи под фильтрами params в порядке сортировки. Вызывается под s.mu.
+func (s *Storage) matched(params postgre.ListParams) []record {
+	var matched []record
+	for _, rec := range s.records {
+		if matchesList(rec.fields, params) {
+			matched = append(matched, rec)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compare(matched[i], matched[j], params) < 0
+	})
+
+	return matched
+}
+
+// aggregate учитывает в billing.Aggregator подписки, пересекающиеся с периодом и подходящие под фильтры.
+func (s *Storage) aggregate(start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy postgre.ReportGroupBy) *billing.Aggregator {
+	start_date = truncateDate(start_date)
+	end_date = truncateDate(end_date)
+	user_id = strings.ToLower(user_id)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	aggregator := billing.NewAggregator(groupBy, start_date, end_date)
+	for _, rec := range s.records {
+		if inRange(rec.fields, start_date, end_date, service_name, user_id) {
+			aggregator.Add(withID(rec))
+		}
+	}
+	return aggregator
+}
+
+// find возвращает индекс записи по ключу или -1.
+// Ключ из имени сервиса и ID пользователя указывает на последний (по start_date) период подписки.
+func (s *Storage) find(key postgre.SubscriptionKey) int {
+	userID := strings.ToLower(key.UserID)
+	found := -1
+	for i, rec := range s.records {
+		if key.ID != 0 {
+			if rec.id == key.ID {
+				return i
+			}
+			continue
+		}
+		if rec.fields.ServiceName != key.ServiceName || rec.fields.UserId != userID {
+			continue
+		}
+		if found < 0 || rec.fields.StartDate.After(s.records[found].fields.StartDate) {
+			found = i
+		}
+	}
+	return found
+}
+
+// findOverlap возвращает индекс периода той же пары (service_name, user_id), пересекающегося с rb,
+// не считая записи excludeID, или -1.
+func (s *Storage) findOverlap(rb postgre.RequestFields, excludeID int64) int {
+	for i, rec := range s.records {
+		if rec.id == excludeID || rec.fields.ServiceName != rb.ServiceName || rec.fields.UserId != rb.UserId {
+			continue
+		}
+		if rb.EndDate != nil && rec.fields.StartDate.After(*rb.EndDate) {
+			continue
+		}
+		if rec.fields.EndDate != nil && rec.fields.EndDate.Before(rb.StartDate) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// inRange сообщает, пересекается ли подписка с периодом и подходит ли под фильтры RangePrice.
+func inRange(rb postgre.RequestFields, start_date, end_date time.Time, service_name, user_id string) bool {
+	if rb.StartDate.After(end_date) {
+		return false
+	}
+	if rb.EndDate != nil && rb.EndDate.Before(start_date) {
+		return false
+	}
+	if service_name != "" && rb.ServiceName != service_name {
+		return false
+	}
+	if user_id != "" && rb.UserId != user_id {
+		return false
+	}
+	return true
+}
+
+// matchesList сообщает, подходит ли подписка под фильтры списка.
+func matchesList(rb postgre.RequestFields, params postgre.ListParams) bool {
+	if params.ServiceName != "" && rb.ServiceName != params.ServiceName {
+		return false
+	}
+	if params.UserID != "" && rb.UserId != strings.ToLower(params.UserID) {
+		return false
+	}
+	if params.ActiveOn != nil {
+		activeOn := truncateDate(*params.ActiveOn)
+		if rb.StartDate.After(activeOn) || (rb.EndDate != nil && rb.EndDate.Before(activeOn)) {
+			return false
+		}
+	}
+	if params.PriceMin != nil && rb.Price < *params.PriceMin {
+		return false
+	}
+	if params.PriceMax != nil && rb.Price > *params.PriceMax {
+		return false
+	}
+	return true
+}
+
+// compare сравнивает записи по полю сортировки, а при равенстве - по id, с учетом направления сортировки.
+func compare(a, b record, params postgre.ListParams) int {
+	c := 0
+	switch params.Sort {
+	case postgre.SortByPrice:
+		c = cmp.Compare(a.fields.Price, b.fields.Price)
+	case postgre.SortByStartDate:
+		c = a.fields.StartDate.Compare(b.fields.StartDate)
+	case postgre.SortByServiceName:
+		c = strings.Compare(a.fields.ServiceName, b.fields.ServiceName)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.id, b.id)
+	}
+	if params.Desc {
+		c = -c
+	}
+	return c
+}
+
+// cursorRecord строит из курсора запись, с которой сравниваются записи списка.
+func cursorRecord(c *postgre.ListCursor) (record, error) {
+	rec := record{id: c.ID}
+
+	value, err := postgre.CursorArg(c)
+	if err != nil {
+		return rec, err
+	}
+
+	switch c.Sort {
+	case postgre.SortByPrice:
+		rec.fields.Price = uint16(value.(int64))
+	case postgre.SortByStartDate:
+		rec.fields.StartDate, _ = time.Parse(time.DateOnly, value.(string))
+	case postgre.SortByServiceName:
+		rec.fields.ServiceName = value.(string)
+	}
+
+	return rec, nil
+}
+
+// withID возвращает копию полей записи с заполненными ID и версией.
+func withID(rec record) postgre.RequestFields {
+	rb := clone(rec.fields)
+	rb.ID = rec.id
+	rb.Version = rec.version
+	return rb
+}
+
+// normalize приводит поля к виду, в котором их вернула бы postgres: UUID в нижнем регистре, даты без времени.
+func normalize(rb postgre.RequestFields) postgre.RequestFields {
+	rb.UserId = strings.ToLower(rb.UserId)
+	rb.StartDate = truncateDate(rb.StartDate)
+	rb.EndDate = truncateDatePtr(rb.EndDate)
+	return rb
+}
+
+func clone(rb postgre.RequestFields) postgre.RequestFields {
+	if rb.EndDate != nil {
+		endDate := *rb.EndDate
+		rb.EndDate = &endDate
+	}
+	return rb
+}
+
+func truncateDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func truncateDatePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	date := truncateDate(*t)
+	return &date
+}
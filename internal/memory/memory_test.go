@@ -0,0 +1,14 @@
+package memory_test
+
+import (
+	"testing"
+
+	"gotest_23.07.25/internal/memory"
+	"gotest_23.07.25/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		return memory.New()
+	})
+}
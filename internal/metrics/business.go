@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// businessTimeout ограничивает время расчета бизнес-метрик при одном сборе.
+const businessTimeout = 5 * time.Second
+
+// Reporter считает расходы на подписки за период.
+type Reporter interface {
+	Report(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy postgre.ReportGroupBy) ([]postgre.ReportGroup, error)
+}
+
+// businessCollector считает бизнес-метрики при каждом сборе: число подписок, активных
+// в текущем месяце, и расходы на них за месяц по сервисам.
+type businessCollector struct {
+	log      *slog.Logger
+	reporter Reporter
+
+	active *prometheus.Desc
+	spend  *prometheus.Desc
+	up     *prometheus.Desc
+}
+
+// NewBusinessCollector возвращает коллектор бизнес-метрик. Месяц считается так же, как в отчете о расходах.
+func NewBusinessCollector(log *slog.Logger, reporter Reporter) prometheus.Collector {
+	return &businessCollector{
+		log:      log.With(slog.String("component", "metrics/business")),
+		reporter: reporter,
+		active: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "active"),
+			"Number of subscriptions active in the current month by service.",
+			[]string{"service_name"}, nil,
+		),
+		spend: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "monthly_spend"),
+			"Spend on subscriptions in the current month by service.",
+			[]string{"service_name"}, nil,
+		),
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "business_metrics_up"),
+			"Whether business metrics were calculated at the last scrape.",
+			nil, nil,
+		),
+	}
+}
+
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.active
+	ch <- c.spend
+	ch <- c.up
+}
+
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), businessTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+
+	groups, err := c.reporter.Report(ctx, start, end, "", "", postgre.ReportGroupBy{ServiceName: true})
+	if err != nil {
+		c.log.Error("Failed to calculate business metrics", slog.String("error", err.Error()))
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	for _, g := range groups {
+		ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(g.Count), g.ServiceName)
+		ch <- prometheus.MustNewConstMetric(c.spend, prometheus.GaugeValue, float64(g.Price), g.ServiceName)
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+}
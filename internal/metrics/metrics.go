@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace - префикс имен метрик сервиса.
+const namespace = "subscriptions"
+
+// Metrics - реестр метрик сервиса и метрики, которые обновляют middleware и хранилище.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	// HTTPRequests и HTTPDuration размечены методом, шаблоном маршрута chi и статусом ответа.
+	HTTPRequests *prometheus.CounterVec
+	HTTPDuration *prometheus.HistogramVec
+	HTTPInFlight prometheus.Gauge
+
+	// GRPCRequests и GRPCDuration размечены полным именем метода gRPC и кодом ответа.
+	GRPCRequests *prometheus.CounterVec
+	GRPCDuration *prometheus.HistogramVec
+	GRPCInFlight prometheus.Gauge
+
+	// StorageDuration и StorageErrors размечены операцией хранилища; ошибки - еще и видом ошибки.
+	StorageDuration *prometheus.HistogramVec
+	StorageErrors   *prometheus.CounterVec
+}
+
+// New создает реестр с метриками HTTP, gRPC, хранилища, рантайма Go и процесса.
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests by method, route pattern and status.",
+		}, []string{"method", "route", "status"}),
+		HTTPDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route pattern and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		HTTPInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests being served.",
+		}),
+		GRPCRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Number of gRPC calls by method and status code.",
+		}, []string{"method", "code"}),
+		GRPCDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "gRPC call latency by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		GRPCInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_in_flight",
+			Help:      "Number of gRPC calls being served.",
+		}),
+		StorageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "operation_duration_seconds",
+			Help:      "Storage operation latency by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		StorageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "operation_errors_total",
+			Help:      "Number of failed storage operations by operation and error kind.",
+		}, []string{"operation", "kind"}),
+	}
+
+	m.Registry.MustRegister(
+		m.HTTPRequests,
+		m.HTTPDuration,
+		m.HTTPInFlight,
+		m.GRPCRequests,
+		m.GRPCDuration,
+		m.GRPCInFlight,
+		m.StorageDuration,
+		m.StorageErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler возвращает хендлер, отдающий метрики в текстовом формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
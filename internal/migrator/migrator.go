@@ -0,0 +1,196 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"gotest_23.07.25/internal/config"
+	"gotest_23.07.25/migrations"
+)
+
+// versionLayout - формат версии новых миграций, как у существующих.
+const versionLayout = "20060102150405"
+
+// migrationName - допустимое имя новой миграции.
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Migrator применяет и откатывает миграции схемы БД.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// New подключается к БД драйвера driver по storageLink из config.GetStorageLink.
+// Миграции берутся из MIGRATION_PATH (postgres) или SQLITE_MIGRATION_PATH (sqlite),
+// а если переменная не задана - из встроенных в бинарник. Подключение закрывается в Close.
+func New(log *slog.Logger, driver, storageLink string) (*Migrator, error) {
+	const op = "internal.migrator.New"
+
+	dbURL, dir, env, err := target(driver, storageLink)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var m *migrate.Migrate
+	if path := os.Getenv(env); path != "" {
+		m, err = migrate.New(path, dbURL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to open migrations from %s: %w", op, env, err)
+		}
+	} else {
+		src, err := iofs.New(migrations.FS, dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to open embedded migrations: %w", op, err)
+		}
+		m, err = migrate.NewWithSourceInstance("iofs", src, dbURL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to set instance for migration: %w", op, err)
+		}
+	}
+
+	m.Log = logger{log: log.With(slog.String("component", "migrator"))}
+
+	return &Migrator{m: m}, nil
+}
+
+// target возвращает URL БД для golang-migrate, каталог встроенных миграций и переменную окружения с путем к миграциям.
+func target(driver, storageLink string) (dbURL, dir, env string, err error) {
+	switch driver {
+	case config.DriverPostgres:
+		return storageLink, migrations.DirPostgres, "MIGRATION_PATH", nil
+	case config.DriverSQLite:
+		return "sqlite://" + storageLink, migrations.DirSQLite, "SQLITE_MIGRATION_PATH", nil
+	default:
+		return "", "", "", fmt.Errorf("unknown sql driver: %q", driver)
+	}
+}
+
+// Up применяет n следующих миграций, при n = 0 - все. Отсутствие новых миграций не считается ошибкой.
+func (m *Migrator) Up(n int) error {
+	const op = "internal.migrator.Up"
+
+	var err error
+	if n > 0 {
+		err = m.m.Steps(n)
+	} else {
+		err = m.m.Up()
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Down откатывает n последних миграций.
+func (m *Migrator) Down(n int) error {
+	const op = "internal.migrator.Down"
+
+	if n < 1 {
+		return fmt.Errorf("%s: number of migrations must be positive, got %d", op, n)
+	}
+
+	if err := m.m.Steps(-n); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Version возвращает версию примененной схемы и признак прерванной миграции.
+// Для БД без миграций возвращает 0.
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	const op = "internal.migrator.Version"
+
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, dirty, nil
+}
+
+// Force записывает версию схемы version и снимает признак прерванной миграции, не выполняя миграций.
+// Используется после ручного исправления БД; -1 означает схему без миграций.
+func (m *Migrator) Force(version int) error {
+	const op = "internal.migrator.Force"
+
+	if err := m.m.Force(version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Close закрывает подключение к БД и источник миграций.
+func (m *Migrator) Close() error {
+	const op = "internal.migrator.Close"
+
+	srcErr, dbErr := m.m.Close()
+	if err := errors.Join(srcErr, dbErr); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Create создает пустые up- и down-миграции name в каталоге dir для postgres и в dir/sqlite для SQLite
+// с версией по времени now. Возвращает пути созданных файлов.
+func Create(dir, name string, now time.Time) ([]string, error) {
+	const op = "internal.migrator.Create"
+
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("%s: invalid migration name %q, use lowercase letters, digits and underscores", op, name)
+	}
+
+	base := now.UTC().Format(versionLayout) + "_" + name
+
+	var created []string
+	for _, d := range []string{filepath.Join(dir, migrations.DirPostgres), filepath.Join(dir, migrations.DirSQLite)} {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(d, base+"."+direction+".sql")
+
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return created, fmt.Errorf("%s: %w", op, err)
+			}
+			_, err = fmt.Fprintf(f, "-- %s (%s)\n", name, direction)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return created, fmt.Errorf("%s: %w", op, err)
+			}
+
+			created = append(created, path)
+		}
+	}
+
+	return created, nil
+}
+
+// logger передает сообщения golang-migrate в slog.
+type logger struct {
+	log *slog.Logger
+}
+
+func (l logger) Printf(format string, v ...any) {
+	l.log.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l logger) Verbose() bool {
+	return false
+}
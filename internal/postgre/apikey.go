@@ -0,0 +1,124 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey - ключ доступа к API. Сам ключ не хранится: в БД лежит только его хеш.
+// Prefix - начало ключа, по которому его можно узнать в списке.
+type APIKey struct {
+	ID        int64      `json:"id" example:"1"`
+	Name      string     `json:"name" example:"billing-service"`
+	Prefix    string     `json:"prefix" example:"subs_3fA9"`
+	Scopes    []string   `json:"scopes" example:"subscriptions:read,reports:read"`
+	CreatedAt time.Time  `json:"created_at" example:"2025-08-10T10:00:00Z"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" example:"2025-09-01T10:00:00Z"`
+}
+
+// CreateAPIKey сохраняет ключ с хешем hash и возвращает его с присвоенными id и датой создания.
+func (s *Storage) CreateAPIKey(ctx context.Context, key APIKey, hash string) (*APIKey, error) {
+	const op = "internal.postgre.CreateAPIKey"
+	slog.Info("Start create api key tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		key.Name, key.Prefix, hash, pq.Array(key.Scopes)).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to insert into table: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Create api key done successfully", slog.String("op", op), slog.Int64("id", key.ID))
+	return &key, nil
+}
+
+// APIKeyByHash возвращает действующий (не отозванный) ключ по хешу или ErrRecordNotFound.
+func (s *Storage) APIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	const op = "internal.postgre.APIKeyByHash"
+
+	var key APIKey
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, name, prefix, scopes, created_at
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL`,
+		hash).Scan(&key.ID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &key.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to query row: %w", op, classify(err))
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys возвращает все ключи, включая отозванные, в порядке создания.
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	const op = "internal.postgre.ListAPIKeys"
+	slog.Info("Start list api keys", slog.String("op", op))
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, prefix, scopes, created_at, revoked_at
+		FROM api_keys
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to query rows: %w", op, classify(err))
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var key APIKey
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &key.CreatedAt, &key.RevokedAt); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan row: %w", op, classify(err))
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows scan error: %w", op, classify(err))
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ. Для отсутствующего или уже отозванного ключа возвращает ErrRecordNotFound.
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "internal.postgre.RevokeAPIKey"
+	slog.Info("Start revoke api key", slog.String("op", op), slog.Int64("id", id))
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = now()
+		WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("%s: failed to update table: %w", op, classify(err))
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows: %w", op, classify(err))
+	}
+	if n == 0 {
+		return ErrRecordNotFound
+	}
+
+	slog.Info("Revoke api key done successfully", slog.String("op", op))
+	return nil
+}
@@ -0,0 +1,121 @@
+package postgre
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+// Категории ошибок хранилища. Любая ошибка, которую возвращают методы хранилища, относится
+// не больше чем к одной категории; проверяются через errors.Is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnavailable  = errors.New("storage unavailable")
+)
+
+// Error - ошибка хранилища категории Kind. Reason - описание, которое можно показать клиенту,
+// Err - исходная ошибка драйвера, если она есть.
+type Error struct {
+	Kind   error
+	Reason string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Reason + ": " + e.Err.Error()
+	}
+	return e.Reason
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// newError возвращает ошибку категории kind без исходной ошибки. Используется для именованных ошибок пакета.
+func newError(kind error, reason string) error {
+	return &Error{Kind: kind, Reason: reason}
+}
+
+// ErrRecordNotFound означает, что записи о подписке с таким ключом нет.
+var ErrRecordNotFound = newError(ErrNotFound, "record not found")
+
+// ErrorReason возвращает описание ошибки хранилища для клиента и false, если err не относится ни к одной категории.
+func ErrorReason(err error) (string, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Reason, true
+	}
+	return "", false
+}
+
+// IsRowError сообщает, относится ли ошибка создания записи к самой записи (конфликт или некорректные данные).
+// Такие ошибки в CreateBatch попадают в результат записи и не прерывают пакет.
+func IsRowError(err error) bool {
+	return errors.Is(err, ErrConflict) || errors.Is(err, ErrInvalidInput)
+}
+
+// Коды ошибок postgres (https://www.postgresql.org/docs/current/errcodes-appendix.html):
+const (
+	notNullViolation     = "23502"
+	foreignKeyViolation  = "23503"
+	uniqueViolation      = "23505"
+	checkViolation       = "23514"
+	exclusionViolation   = "23P01"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	lockNotAvailable     = "55P03"
+	queryCanceled        = "57014"
+	adminShutdown        = "57P01"
+	crashShutdown        = "57P02"
+	cannotConnectNow     = "57P03"
+)
+
+// classify относит ошибку драйвера postgres к одной из категорий хранилища.
+// Все ошибки класса 22 (data exception) - некорректные данные, классов 08 и 53 - недоступность БД.
+// Ошибки, уже отнесенные к категории, и ошибки без категории возвращаются как есть.
+func classify(err error) error {
+	if err == nil || isClassified(err) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Reason: "record not found", Err: err}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch code := string(pqErr.Code); {
+		case pqErr.Code.Class() == "22", code == notNullViolation,
+			code == foreignKeyViolation, code == checkViolation:
+			return &Error{Kind: ErrInvalidInput, Reason: pqErr.Message, Err: err}
+		case code == uniqueViolation, code == exclusionViolation:
+			return &Error{Kind: ErrConflict, Reason: "record conflicts with an existing one", Err: err}
+		case code == serializationFailure, code == deadlockDetected, code == lockNotAvailable,
+			code == queryCanceled, code == adminShutdown, code == crashShutdown, code == cannotConnectNow,
+			pqErr.Code.Class() == "08", pqErr.Code.Class() == "53":
+			return &Error{Kind: ErrUnavailable, Reason: "storage is temporarily unavailable", Err: err}
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr) {
+		return &Error{Kind: ErrUnavailable, Reason: "storage is temporarily unavailable", Err: err}
+	}
+
+	return err
+}
+
+func isClassified(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) ||
+		errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrUnavailable)
+}
@@ -0,0 +1,158 @@
+package postgre
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// sort fields:
+const (
+	SortByPrice       = "price"
+	SortByStartDate   = "start_date"
+	SortByServiceName = "service_name"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 1000
+)
+
+var ErrInvalidCursor = newError(ErrInvalidInput, "invalid cursor")
+
+// ListParams - параметры выборки страницы списка подписок.
+// Пустые фильтры не применяются. Без Sort записи упорядочены по id.
+type ListParams struct {
+	Limit       int
+	Cursor      *ListCursor
+	ServiceName string
+	UserID      string
+	ActiveOn    *time.Time
+	PriceMin    *uint16
+	PriceMax    *uint16
+	Sort        string
+	Desc        bool
+}
+
+// ListPage - страница списка подписок.
+// NextCursor пустой, если страница последняя, Total - число записей под фильтрами без учета курсора.
+// Total считается заново для каждой страницы: если между запросами страниц записи создают или удаляют,
+// Total может не совпасть с числом записей, полученных по всем страницам.
+type ListPage struct {
+	Subscriptions []RequestFields
+	NextCursor    string
+	Total         uint64
+}
+
+// ListCursor - позиция в списке: значение поля сортировки и id последней отданной записи.
+type ListCursor struct {
+	Sort  string `json:"s,omitempty"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+// EncodeListCursor возвращает непрозрачное строковое представление курсора.
+func EncodeListCursor(c ListCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeListCursor разбирает курсор, полученный от EncodeListCursor, для сортировки sort в направлении desc.
+// Курсор приходит от клиента и не подписан, поэтому проверяется каждое его поле: сортировка и направление
+// должны совпадать с запрошенными, id - быть положительным, а значение - подходить к полю сортировки.
+func DecodeListCursor(s string, sort string, desc bool) (*ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c ListCursor
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Sort != sort || c.Desc != desc {
+		return nil, fmt.Errorf("%w: cursor does not match sort", ErrInvalidCursor)
+	}
+	if c.ID <= 0 {
+		return nil, fmt.Errorf("%w: id must be positive", ErrInvalidCursor)
+	}
+	if _, err := CursorArg(&c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// PageSize возвращает размер страницы: Limit, либо DefaultListLimit, если Limit не задан или больше MaxListLimit.
+func (p ListParams) PageSize() int {
+	if p.Limit <= 0 || p.Limit > MaxListLimit {
+		return DefaultListLimit
+	}
+	return p.Limit
+}
+
+// NextCursor возвращает курсор, указывающий на запись rb при текущей сортировке.
+func (p ListParams) NextCursor(rb RequestFields) string {
+	return EncodeListCursor(ListCursor{
+		Sort:  p.Sort,
+		Desc:  p.Desc,
+		Value: SortValue(rb, p.Sort),
+		ID:    rb.ID,
+	})
+}
+
+// SortValue возвращает значение поля сортировки записи в том виде, в каком оно хранится в курсоре.
+func SortValue(rb RequestFields, sort string) string {
+	switch sort {
+	case SortByPrice:
+		return strconv.FormatUint(uint64(rb.Price), 10)
+	case SortByStartDate:
+		return rb.StartDate.UTC().Format(time.DateOnly)
+	case SortByServiceName:
+		return rb.ServiceName
+	default:
+		return ""
+	}
+}
+
+// CursorArg возвращает значение курсора, приведенное к типу поля сортировки, для передачи в запрос.
+// Значение, которое не могло быть получено от SortValue, возвращает ErrInvalidCursor.
+func CursorArg(c *ListCursor) (any, error) {
+	switch c.Sort {
+	case "":
+		if c.Value != "" {
+			return nil, fmt.Errorf("%w: unexpected value", ErrInvalidCursor)
+		}
+		return nil, nil
+	case SortByPrice:
+		price, err := strconv.ParseUint(c.Value, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("%w: price must be an integer from 0 to 65535", ErrInvalidCursor)
+		}
+		return int64(price), nil
+	case SortByStartDate:
+		if _, err := time.Parse(time.DateOnly, c.Value); err != nil {
+			return nil, fmt.Errorf("%w: start_date must be a date in YYYY-MM-DD format", ErrInvalidCursor)
+		}
+		return c.Value, nil
+	case SortByServiceName:
+		if c.Value == "" || !utf8.ValidString(c.Value) {
+			return nil, fmt.Errorf("%w: service_name must be a non-empty UTF-8 string", ErrInvalidCursor)
+		}
+		return c.Value, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown sort field", ErrInvalidCursor)
+	}
+}
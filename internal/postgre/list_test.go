@@ -0,0 +1,62 @@
+package postgre_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"gotest_23.07.25/internal/postgre"
+)
+
+func TestDecodeListCursor(t *testing.T) {
+	rb := postgre.RequestFields{ID: 7, ServiceName: "Netflix", Price: 100, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	for _, sort := range []string{"", postgre.SortByPrice, postgre.SortByStartDate, postgre.SortByServiceName} {
+		for _, desc := range []bool{false, true} {
+			params := postgre.ListParams{Sort: sort, Desc: desc}
+			c, err := postgre.DecodeListCursor(params.NextCursor(rb), sort, desc)
+			if err != nil {
+				t.Fatalf("sort %q desc %v: %v", sort, desc, err)
+			}
+			if c.ID != rb.ID || c.Value != postgre.SortValue(rb, sort) {
+				t.Errorf("sort %q desc %v: cursor = %+v", sort, desc, c)
+			}
+		}
+	}
+}
+
+func TestDecodeListCursorInvalid(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+		desc   bool
+	}{
+		{"not base64", "%%%", "", false},
+		{"not json", raw(`id=7`), "", false},
+		{"unknown field", raw(`{"id":7,"x":1}`), "", false},
+		{"trailing data", raw(`{"id":7} {}`), "", false},
+		{"other sort", postgre.EncodeListCursor(postgre.ListCursor{Sort: postgre.SortByPrice, Value: "100", ID: 7}), postgre.SortByStartDate, false},
+		{"other direction", postgre.EncodeListCursor(postgre.ListCursor{Sort: postgre.SortByPrice, Value: "100", ID: 7}), postgre.SortByPrice, true},
+		{"zero id", postgre.EncodeListCursor(postgre.ListCursor{}), "", false},
+		{"negative id", postgre.EncodeListCursor(postgre.ListCursor{ID: -1}), "", false},
+		{"value without sort", postgre.EncodeListCursor(postgre.ListCursor{Value: "100", ID: 7}), "", false},
+		{"price out of range", postgre.EncodeListCursor(postgre.ListCursor{Sort: postgre.SortByPrice, Value: "70000", ID: 7}), postgre.SortByPrice, false},
+		{"negative price", postgre.EncodeListCursor(postgre.ListCursor{Sort: postgre.SortByPrice, Value: "-1", ID: 7}), postgre.SortByPrice, false},
+		{"bad date", postgre.EncodeListCursor(postgre.ListCursor{Sort: postgre.SortByStartDate, Value: "2025-13-01", ID: 7}), postgre.SortByStartDate, false},
+		{"empty service name", postgre.EncodeListCursor(postgre.ListCursor{Sort: postgre.SortByServiceName, ID: 7}), postgre.SortByServiceName, false},
+		{"unknown sort", postgre.EncodeListCursor(postgre.ListCursor{Sort: "user_id", Value: "x", ID: 7}), "user_id", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := postgre.DecodeListCursor(tt.cursor, tt.sort, tt.desc)
+			if !errors.Is(err, postgre.ErrInvalidCursor) || !errors.Is(err, postgre.ErrInvalidInput) {
+				t.Errorf("DecodeListCursor() = %+v, %v, want ErrInvalidCursor", c, err)
+			}
+		})
+	}
+}
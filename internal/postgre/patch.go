@@ -0,0 +1,29 @@
+package postgre
+
+import "time"
+
+// ErrInvalidPeriod означает, что дата окончания подписки раньше даты начала.
+var ErrInvalidPeriod = newError(ErrInvalidInput, "end date cannot be before start date")
+
+// PatchFields - частичное изменение подписки по RFC 7396 (JSON Merge Patch).
+// Nil-поле не меняется; EndDateSet с nil EndDate снимает дату окончания.
+type PatchFields struct {
+	Price      *uint16
+	StartDate  *time.Time
+	EndDate    *time.Time
+	EndDateSet bool
+}
+
+// Apply возвращает копию rb с примененными изменениями.
+func (p PatchFields) Apply(rb RequestFields) RequestFields {
+	if p.Price != nil {
+		rb.Price = *p.Price
+	}
+	if p.StartDate != nil {
+		rb.StartDate = *p.StartDate
+	}
+	if p.EndDateSet {
+		rb.EndDate = p.EndDate
+	}
+	return rb
+}
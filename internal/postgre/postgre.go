@@ -1,25 +1,31 @@
 package postgre
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
-	"os"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type RequestFields struct {
+	ID          int64      `json:"id,omitempty" example:"1"`
 	ServiceName string     `json:"service_name" example:"Google"`
 	Price       uint16     `json:"price" example:"100"`
 	UserId      string     `json:"user_id" example:"b1d4c0ec-9a4a-4e3a-9fdd-5e27d0be16fa"`
 	StartDate   time.Time  `json:"start_date" example:"2025-01-01T00:00:00Z"`
 	EndDate     *time.Time `json:"end_date,omitempty" example:"2025-12-31T00:00:00Z"`
+	Version     int64      `json:"version,omitempty" example:"1"`
+}
+
+// SubscriptionKey определяет запись о подписке: по ID, если он задан, иначе по имени сервиса и ID пользователя.
+type SubscriptionKey struct {
+	ID          int64
+	ServiceName string
+	UserID      string
 }
 
 type RequestUpdateFields struct {
@@ -28,12 +34,79 @@ type RequestUpdateFields struct {
 	EndDate   *time.Time `json:"end_date,omitempty" example:"2025-12-31T00:00:00Z"`
 }
 
+// ReportGroupBy задает поля, по которым группируется отчет о расходах.
+type ReportGroupBy struct {
+	ServiceName bool
+	UserID      bool
+	Month       bool
+}
+
+// ReportGroup - строка отчета о расходах. Поля, не участвующие в группировке, пустые.
+type ReportGroup struct {
+	ServiceName string `json:"service_name,omitempty" example:"Google"`
+	UserId      string `json:"user_id,omitempty" example:"b1d4c0ec-9a4a-4e3a-9fdd-5e27d0be16fa"`
+	Month       string `json:"month,omitempty" example:"2025-01"`
+	Price       uint64 `json:"price" example:"1200"`
+	Count       uint64 `json:"count" example:"3"`
+}
+
+// billedQuery строит оплачиваемые месяцы подписок, пересекающихся с периодом [$1, $2], с фильтрами
+// по имени сервиса ($3) и ID пользователя ($4). Каждый месяц оплачивается для пары (service_name, user_id)
+// один раз: если в нем активны несколько периодов, берется период, начавшийся позже. Общий для RangePrice и Report.
+const billedQuery = `
+	WITH billed AS (
+		SELECT DISTINCT ON (service_name, user_id, month) id, service_name, user_id, price, month
+		FROM subscriptions,
+			generate_series(
+				date_trunc('month', GREATEST(start_date, $1::date)),
+				date_trunc('month', LEAST(COALESCE(end_date, $2::date), $2::date)),
+				interval '1 month'
+			) AS month
+		WHERE start_date <= $2::date
+			AND (end_date is NULL OR end_date >= $1::date)
+			AND ($3 = '' OR service_name = $3)
+			AND ($4 = '' OR user_id = $4::uuid)
+		ORDER BY service_name, user_id, month, start_date DESC
+	)
+`
+
 type Storage struct {
 	db *sql.DB
 }
 
-var ErrSubscriptionExists = errors.New("subscription already exists")
+// ErrSubscriptionExists означает, что период подписки пересекается с уже существующим периодом
+// той же пары (service_name, user_id).
+var ErrSubscriptionExists = newError(ErrConflict, "subscription period overlaps an existing one")
 
+// OverlapError - ошибка ErrSubscriptionExists с описанием периода, с которым произошло пересечение.
+type OverlapError struct {
+	Existing RequestFields
+}
+
+func (e *OverlapError) Error() string {
+	end := "open"
+	if e.Existing.EndDate != nil {
+		end = e.Existing.EndDate.Format(time.DateOnly)
+	}
+	return fmt.Sprintf("%s: id %d, %s - %s", ErrSubscriptionExists, e.Existing.ID, e.Existing.StartDate.Format(time.DateOnly), end)
+}
+
+func (e *OverlapError) Unwrap() error {
+	return ErrSubscriptionExists
+}
+
+// BatchResult - результат создания одной записи пакета в CreateBatch: id созданной записи или ошибка записи.
+// Нулевой ID без ошибки означает, что запись не создана из-за отката всего пакета.
+type BatchResult struct {
+	ID  int64
+	Err error
+}
+
+// ErrVersionMismatch означает, что версия записи не совпала с ожидаемой: запись изменили после того,
+// как клиент ее прочитал.
+var ErrVersionMismatch = newError(ErrConflict, "subscription version mismatch")
+
+// New открывает пул соединений с БД postgres по storageLink. Миграции применяются пакетом migrator.
 func New(storageLink string) (*Storage, error) {
 	const op = "internal.postgre.New"
 
@@ -46,263 +119,686 @@ func New(storageLink string) (*Storage, error) {
 	db.SetConnMaxIdleTime(20)
 	db.SetConnMaxLifetime(30 * time.Minute)
 
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		return nil, fmt.Errorf("%s: failed to set driver for migration: %w", op, err)
-	}
-
-	m, err := migrate.NewWithDatabaseInstance(
-		os.Getenv("MIGRATION_PATH"),
-		"postgres",
-		driver,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("%s: failed to set instance for migration: %w", op, err)
-	}
-
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return nil, fmt.Errorf("%s: failed to apply migration: %w", op, err)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: failed to connect: %w", op, err)
 	}
 
 	return &Storage{db: db}, nil
 }
 
 // Create создает новую запись о подписке в таблице.
-func (s *Storage) Create(rb RequestFields) (string, error) {
+// Возвращает id новой записи.
+func (s *Storage) Create(ctx context.Context, rb RequestFields) (int64, error) {
 	const op = "internal.postgre.Create"
 	slog.Info("Start create tx", slog.String("op", op))
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return "", fmt.Errorf("%s: failed to begin tx: %w", op, err)
+		return 0, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
 	}
 	defer rollback(tx, op)
 
-	res, err := tx.Exec(`
-		INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date)
-		VALUES($1, $2, $3::uuid, $4, $5)
-		ON CONFLICT (service_name, user_id) DO NOTHING
-	`, rb.ServiceName, rb.Price, rb.UserId, rb.StartDate, rb.EndDate)
+	id, err := insert(ctx, tx, rb)
 	if err != nil {
-		return "", fmt.Errorf("%s: failed to insert into table: %w", op, err)
+		if errors.Is(err, ErrSubscriptionExists) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Create done successfully", slog.String("op", op), slog.Int64("id", id))
+	return id, nil
+}
+
+// CreateBatch создает записи о подписках одной транзакцией и возвращает результат по каждой записи.
+// Записи, пересекающиеся с существующими периодами (в том числе с предыдущими записями пакета),
+// получают ErrSubscriptionExists, нарушающие ограничения таблицы - ошибку ErrInvalidInput, остальные - id. Если atomic и хотя бы одна запись не создана,
+// транзакция откатывается и id всех записей обнуляются.
+func (s *Storage) CreateBatch(ctx context.Context, rows []RequestFields, atomic bool) ([]BatchResult, error) {
+	const op = "internal.postgre.CreateBatch"
+	slog.Info("Start create batch tx", slog.String("op", op), slog.Int("rows", len(rows)))
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return "", fmt.Errorf("%s: failed to read sql result: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
 	}
+	defer rollback(tx, op)
+
+	results := make([]BatchResult, len(rows))
+	failed := false
 
-	if rowsAffected == 0 {
-		slog.Info("Subsctibtion already exists", slog.String("service_name", rb.ServiceName), slog.String("user_id", rb.UserId))
-		return "", ErrSubscriptionExists
+	for i, rb := range rows {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_row`); err != nil {
+			return nil, fmt.Errorf("%s: failed to set savepoint: %w", op, classify(err))
+		}
+
+		id, err := insert(ctx, tx, rb)
+		if err != nil {
+			if !IsRowError(err) {
+				return nil, fmt.Errorf("%s: row %d: %w", op, i+1, classify(err))
+			}
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_row`); err != nil {
+				return nil, fmt.Errorf("%s: failed to rollback to savepoint: %w", op, classify(err))
+			}
+			results[i].Err = err
+			failed = true
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT batch_row`); err != nil {
+			return nil, fmt.Errorf("%s: failed to release savepoint: %w", op, classify(err))
+		}
+		results[i].ID = id
+	}
+
+	if atomic && failed {
+		for i := range results {
+			results[i].ID = 0
+		}
+		slog.Info("Create batch rolled back", slog.String("op", op))
+		return results, nil
 	}
 
 	if err = tx.Commit(); err != nil {
-		return "", fmt.Errorf("%s: failed to commit: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
 	}
 
-	slog.Info("Create done successfully", slog.String("op", op))
-	return rb.UserId, nil
+	slog.Info("Create batch done successfully", slog.String("op", op))
+	return results, nil
 }
 
-// Read возвращает информацию о подписке по имени сервиса и ID пользователя.
-func (s *Storage) Read(service_name, user_id string) (*RequestFields, error) {
+// Read возвращает информацию о подписке по ключу: id или имени сервиса и ID пользователя.
+func (s *Storage) Read(ctx context.Context, key SubscriptionKey) (*RequestFields, error) {
 	const op = "internal.postgre.Read"
 	slog.Info("Start read tx", slog.String("op", op))
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
 	}
 	defer rollback(tx, op)
 
 	var rb RequestFields
 
-	err = tx.QueryRow(`
-		SELECT service_name, price, user_id, start_date, end_date
+	where, args := keyFilter(key, 1)
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, service_name, price, user_id, start_date, end_date, version
 		FROM subscriptions
-		WHERE service_name = $1 AND user_id = $2::uuid
-	`, service_name, user_id).Scan(&rb.ServiceName, &rb.Price, &rb.UserId, &rb.StartDate, &rb.EndDate)
+		WHERE `+where, args...).Scan(&rb.ID, &rb.ServiceName, &rb.Price, &rb.UserId, &rb.StartDate, &rb.EndDate, &rb.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, sql.ErrNoRows
+			return nil, ErrRecordNotFound
 		}
-		return nil, fmt.Errorf("%s: failed to query row: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to query row: %w", op, classify(err))
 	}
 
 	if err = tx.Commit(); err != nil {
-		return nil, fmt.Errorf("%s: failed to commit: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
 	}
 
 	slog.Info("Read done successfully", slog.String("op", op))
 	return &rb, nil
 }
 
-// Update обновляет информацию о подписке в таблице.
-func (s *Storage) Update(service_name, user_id string, rb RequestUpdateFields) error {
+// Update обновляет информацию о подписке в таблице и возвращает новую версию записи.
+// Если version не 0, запись обновляется только при совпадении версии, иначе возвращается ErrVersionMismatch.
+// Дата окончания раньше даты начала дает ErrInvalidPeriod, как в Patch.
+func (s *Storage) Update(ctx context.Context, key SubscriptionKey, rb RequestUpdateFields, version int64) (int64, error) {
 	const op = "internal.postgre.Update"
 	slog.Info("Start update tx", slog.String("op", op))
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%s: failed to begin tx: %w", op, err)
+		return 0, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
 	}
 	defer rollback(tx, op)
 
-	res, err := tx.Exec(`
-		UPDATE subscriptions
-		SET price = $1, start_date = $2, end_date = $3
-		WHERE service_name = $4 AND user_id = $5::uuid
-	`, rb.Price, rb.StartDate, rb.EndDate, service_name, user_id)
+	where, args := keyFilter(key, 1)
+
+	var (
+		id          int64
+		serviceName string
+		userID      string
+		current     int64
+	)
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, service_name, user_id, version
+		FROM subscriptions
+		WHERE `+where+`
+		FOR UPDATE
+	`, args...).Scan(&id, &serviceName, &userID, &current)
 	if err != nil {
-		return fmt.Errorf("%s: failed to update table: %w", op, err)
+		if err == sql.ErrNoRows {
+			return 0, ErrRecordNotFound
+		}
+		return 0, fmt.Errorf("%s: failed to query row: %w", op, classify(err))
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	if version != 0 && version != current {
+		return 0, ErrVersionMismatch
+	}
+
+	if rb.EndDate != nil && rb.EndDate.Before(rb.StartDate) {
+		return 0, ErrInvalidPeriod
+	}
+
+	existing, err := findOverlap(ctx, tx, serviceName, userID, rb.StartDate, rb.EndDate, id)
 	if err != nil {
-		return fmt.Errorf("%s: failed to read sql result: %w", op, err)
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if existing != nil {
+		return 0, &OverlapError{Existing: *existing}
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	err = tx.QueryRowContext(ctx, `
+		UPDATE subscriptions
+		SET price = $1, start_date = $2, end_date = $3, version = version + 1
+		WHERE id = $4
+		RETURNING version
+	`, rb.Price, rb.StartDate, rb.EndDate, id).Scan(&current)
+	if err != nil {
+		if isExclusionViolation(err) {
+			return 0, ErrSubscriptionExists
+		}
+		return 0, fmt.Errorf("%s: failed to update table: %w", op, classify(err))
 	}
 
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%s: failed to commit: %w", op, err)
+		return 0, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
 	}
 
 	slog.Info("Update done successfully", slog.String("op", op))
-	return nil
+	return current, nil
+}
+
+// Patch частично изменяет подписку (см. PatchFields) и возвращает ее полную запись после изменения.
+// Версия проверяется так же, как в Update.
+func (s *Storage) Patch(ctx context.Context, key SubscriptionKey, patch PatchFields, version int64) (*RequestFields, error) {
+	const op = "internal.postgre.Patch"
+	slog.Info("Start patch tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	var rb RequestFields
+
+	where, args := keyFilter(key, 1)
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, service_name, price, user_id, start_date, end_date, version
+		FROM subscriptions
+		WHERE `+where+`
+		FOR UPDATE
+	`, args...).Scan(&rb.ID, &rb.ServiceName, &rb.Price, &rb.UserId, &rb.StartDate, &rb.EndDate, &rb.Version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to query row: %w", op, classify(err))
+	}
+
+	if version != 0 && version != rb.Version {
+		return nil, ErrVersionMismatch
+	}
+
+	rb = patch.Apply(rb)
+	if rb.EndDate != nil && rb.EndDate.Before(rb.StartDate) {
+		return nil, ErrInvalidPeriod
+	}
+
+	existing, err := findOverlap(ctx, tx, rb.ServiceName, rb.UserId, rb.StartDate, rb.EndDate, rb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if existing != nil {
+		return nil, &OverlapError{Existing: *existing}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE subscriptions
+		SET price = $1, start_date = $2, end_date = $3, version = version + 1
+		WHERE id = $4
+		RETURNING start_date, end_date, version
+	`, rb.Price, rb.StartDate, rb.EndDate, rb.ID).Scan(&rb.StartDate, &rb.EndDate, &rb.Version)
+	if err != nil {
+		if isExclusionViolation(err) {
+			return nil, ErrSubscriptionExists
+		}
+		return nil, fmt.Errorf("%s: failed to update table: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Patch done successfully", slog.String("op", op))
+	return &rb, nil
 }
 
 // Delete удаляет запись о подписке из таблицы.
-func (s *Storage) Delete(service_name, user_id string) error {
+// Версия проверяется так же, как в Update.
+func (s *Storage) Delete(ctx context.Context, key SubscriptionKey, version int64) error {
 	const op = "internal.postgre.Delete"
 	slog.Info("Start delete tx", slog.String("op", op))
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%s: failed to begin tx: %w", op, err)
+		return fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
 	}
 	defer rollback(tx, op)
 
-	res, err := tx.Exec(`
-		DELETE FROM subscriptions
-		WHERE service_name = $1 AND user_id = $2::uuid
-	`, service_name, user_id)
+	where, args := keyFilter(key, 1)
+
+	var id, current int64
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, version
+		FROM subscriptions
+		WHERE `+where+`
+		FOR UPDATE
+	`, args...).Scan(&id, &current)
 	if err != nil {
-		return fmt.Errorf("%s: failed to delete from table: %w", op, err)
+		if err == sql.ErrNoRows {
+			return ErrRecordNotFound
+		}
+		return fmt.Errorf("%s: failed to query row: %w", op, classify(err))
 	}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("%s: failed to read sql result: %w", op, err)
+	if version != 0 && version != current {
+		return ErrVersionMismatch
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("%s: failed to delete from table: %w", op, classify(err))
 	}
 
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%s: failed to commit: %w", op, err)
+		return fmt.Errorf("%s: failed to commit: %w", op, classify(err))
 	}
 
 	slog.Info("Delete done successfully", slog.String("op", op))
 	return nil
 }
 
-// List возвращает список всех подписок в таблице.
-func (s *Storage) List() ([]RequestFields, error) {
+// List возвращает страницу списка подписок с фильтрами, сортировкой и курсором из params.
+// Для постраничного обхода используется keyset-пагинация по паре (поле сортировки, id).
+func (s *Storage) List(ctx context.Context, params ListParams) (*ListPage, error) {
 	const op = "internal.postgre.List"
 	slog.Info("Start list tx", slog.String("op", op))
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
 	}
 	defer rollback(tx, op)
 
-	rows, err := tx.Query(`
-		SELECT service_name, price, user_id, start_date, end_date
+	where, args := listFilter(params)
+
+	page := &ListPage{Subscriptions: []RequestFields{}}
+
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM subscriptions WHERE `+where, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("%s: failed to count rows: %w", op, classify(err))
+	}
+
+	where, order, args, err := listOrder(params, where, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+
+	limit := params.PageSize()
+	args = append(args, limit+1)
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, service_name, price, user_id, start_date, end_date, version
 		FROM subscriptions
-	`)
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d
+	`, where, order, len(args)), args...)
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to query rows: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to query rows: %w", op, classify(err))
 	}
 	defer rows.Close()
 
-	var subscriptions []RequestFields
-
 	for rows.Next() {
 		var rb RequestFields
-		if err := rows.Scan(&rb.ServiceName, &rb.Price, &rb.UserId, &rb.StartDate, &rb.EndDate); err != nil {
-			return nil, fmt.Errorf("%s: failed to scan row: %w", op, err)
+		if err := rows.Scan(&rb.ID, &rb.ServiceName, &rb.Price, &rb.UserId, &rb.StartDate, &rb.EndDate, &rb.Version); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan row: %w", op, classify(err))
 		}
-		subscriptions = append(subscriptions, rb)
+		page.Subscriptions = append(page.Subscriptions, rb)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: rows scan error: %w", op, err)
+		return nil, fmt.Errorf("%s: rows scan error: %w", op, classify(err))
 	}
 
 	if err = tx.Commit(); err != nil {
-		return nil, fmt.Errorf("%s: failed to commit: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	if len(page.Subscriptions) > limit {
+		page.Subscriptions = page.Subscriptions[:limit]
+		page.NextCursor = params.NextCursor(page.Subscriptions[limit-1])
 	}
 
 	slog.Info("List done successfully", slog.String("op", op))
-	return subscriptions, nil
+	return page, nil
+}
+
+// Export передает в fn подписки под фильтрами, сортировкой и курсором params по мере чтения из БД,
+// без ограничения на размер страницы. Ошибка fn прерывает выгрузку и возвращается из Export.
+func (s *Storage) Export(ctx context.Context, params ListParams, fn func(RequestFields) error) error {
+	const op = "internal.postgre.Export"
+	slog.Info("Start export tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	where, args := listFilter(params)
+
+	where, order, args, err := listOrder(params, where, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, service_name, price, user_id, start_date, end_date, version
+		FROM subscriptions
+		WHERE %s
+		ORDER BY %s
+	`, where, order), args...)
+	if err != nil {
+		return fmt.Errorf("%s: failed to query rows: %w", op, classify(err))
+	}
+	defer rows.Close()
+
+	var count int
+	for rows.Next() {
+		var rb RequestFields
+		if err := rows.Scan(&rb.ID, &rb.ServiceName, &rb.Price, &rb.UserId, &rb.StartDate, &rb.EndDate, &rb.Version); err != nil {
+			return fmt.Errorf("%s: failed to scan row: %w", op, classify(err))
+		}
+		if err := fn(rb); err != nil {
+			return fmt.Errorf("%s: %w", op, classify(err))
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: rows scan error: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Export done successfully", slog.String("op", op), slog.Int("count", count))
+	return nil
 }
 
 // RangePrice возвращает общую стоимость подписок за указанный диапазон дат и по указанным имени сервиса и id пользователя.
-func (s *Storage) RangePrice(start_date time.Time, end_date time.Time, service_name string, user_id string) (uint64, error) {
+// Цена считается ежемесячной и умножается на число оплачиваемых месяцев пересечения подписки с периодом:
+// месяц оплачивается целиком, если подписка активна в нем хотя бы один день (см. billing.BilledMonths).
+// Если в месяце активны несколько периодов одной пары (service_name, user_id), месяц оплачивается один раз.
+func (s *Storage) RangePrice(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string) (uint64, error) {
 	const op = "internal.postgre.RangePrice"
 	slog.Info("Start range price tx", slog.String("op", op))
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, fmt.Errorf("%s: failed to begin tx: %w", op, err)
+		return 0, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
 	}
 	defer rollback(tx, op)
 
 	var totalPrice uint64
 
-	err = tx.QueryRow(`
-		SELECT SUM(price)
-		FROM subscriptions
-		WHERE start_date <= $2 
-			AND (end_date is NULL OR end_date >= $1)
-			AND ($3 = '' OR service_name = $3)
-			AND ($4 = '' OR user_id = $4::uuid)
+	err = tx.QueryRowContext(ctx, billedQuery+`
+		SELECT COALESCE(SUM(price), 0)::bigint
+		FROM billed
 	`, start_date, end_date, service_name, user_id).Scan(&totalPrice)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, fmt.Errorf("%s: no subscriptions found in the specified date range", op)
 		}
-		return 0, fmt.Errorf("%s: failed to query total price: %w", op, err)
+		return 0, fmt.Errorf("%s: failed to query total price: %w", op, classify(err))
 	}
 
 	if err = tx.Commit(); err != nil {
-		return 0, fmt.Errorf("%s: failed to commit: %w", op, err)
+		return 0, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
 	}
 
 	slog.Info("Range price done successfully", slog.String("op", op))
 	return totalPrice, nil
 }
 
+// Report возвращает расходы на подписки за период, сгруппированные по полям groupBy.
+// Отбор подписок и правило подсчета месяцев совпадают с RangePrice.
+func (s *Storage) Report(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy ReportGroupBy) ([]ReportGroup, error) {
+	const op = "internal.postgre.Report"
+	slog.Info("Start report tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	rows, err := tx.QueryContext(ctx, billedQuery+`
+		SELECT
+			CASE WHEN $5::boolean THEN service_name ELSE '' END,
+			CASE WHEN $6::boolean THEN user_id::text ELSE '' END,
+			CASE WHEN $7::boolean THEN to_char(month, 'YYYY-MM') ELSE '' END,
+			SUM(price)::bigint,
+			COUNT(DISTINCT id)
+		FROM billed
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3
+	`, start_date, end_date, service_name, user_id, groupBy.ServiceName, groupBy.UserID, groupBy.Month)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to query rows: %w", op, classify(err))
+	}
+	defer rows.Close()
+
+	groups := []ReportGroup{}
+
+	for rows.Next() {
+		var g ReportGroup
+		if err := rows.Scan(&g.ServiceName, &g.UserId, &g.Month, &g.Price, &g.Count); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan row: %w", op, classify(err))
+		}
+		groups = append(groups, g)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows scan error: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Report done successfully", slog.String("op", op))
+	return groups, nil
+}
+
 func (s *Storage) Close() error {
 	const op = "internal.postgre.Close"
 	slog.Info("Start close db connection", slog.String("op", op))
 
 	if err := s.db.Close(); err != nil {
-		return fmt.Errorf("%s: failed to close db connection: %w", op, err)
+		return fmt.Errorf("%s: failed to close db connection: %w", op, classify(err))
 	}
 
 	slog.Info("Close db connection done successfully", slog.String("op", op))
 	return nil
 }
 
+// keyFilter возвращает условие WHERE для ключа подписки; плейсхолдеры нумеруются с first.
+// Ключ из имени сервиса и ID пользователя указывает на последний (по start_date) период подписки.
+func keyFilter(key SubscriptionKey, first int) (string, []any) {
+	if key.ID != 0 {
+		return fmt.Sprintf("id = $%d", first), []any{key.ID}
+	}
+	return fmt.Sprintf(`id = (
+		SELECT id FROM subscriptions
+		WHERE service_name = $%d AND user_id = $%d::uuid
+		ORDER BY start_date DESC
+		LIMIT 1
+	)`, first, first+1), []any{key.ServiceName, key.UserID}
+}
+
+// insert добавляет запись о подписке в транзакции tx и возвращает ее id.
+// Если период пересекается с существующим, возвращает OverlapError или ErrSubscriptionExists.
+func insert(ctx context.Context, tx *sql.Tx, rb RequestFields) (int64, error) {
+	existing, err := findOverlap(ctx, tx, rb.ServiceName, rb.UserId, rb.StartDate, rb.EndDate, 0)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		slog.Info("Subsctibtion period overlaps", slog.String("service_name", rb.ServiceName), slog.String("user_id", rb.UserId), slog.Int64("existing_id", existing.ID))
+		return 0, &OverlapError{Existing: *existing}
+	}
+
+	var id int64
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date)
+		VALUES($1, $2, $3::uuid, $4, $5)
+		RETURNING id
+	`, rb.ServiceName, rb.Price, rb.UserId, rb.StartDate, rb.EndDate).Scan(&id)
+	if err != nil {
+		if isExclusionViolation(err) {
+			return 0, ErrSubscriptionExists
+		}
+		return 0, fmt.Errorf("failed to insert into table: %w", classify(err))
+	}
+
+	return id, nil
+}
+
+// findOverlap возвращает период подписки пары (serviceName, userID), пересекающийся с [startDate, endDate],
+// не считая записи excludeID, или nil, если пересечений нет.
+func findOverlap(ctx context.Context, tx *sql.Tx, serviceName, userID string, startDate time.Time, endDate *time.Time, excludeID int64) (*RequestFields, error) {
+	var rb RequestFields
+
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, service_name, price, user_id, start_date, end_date, version
+		FROM subscriptions
+		WHERE service_name = $1 AND user_id = $2::uuid AND id <> $3
+			AND daterange(start_date, end_date, '[]') && daterange($4::date, $5::date, '[]')
+		ORDER BY start_date
+		LIMIT 1
+	`, serviceName, userID, excludeID, startDate, endDate).Scan(&rb.ID, &rb.ServiceName, &rb.Price, &rb.UserId, &rb.StartDate, &rb.EndDate, &rb.Version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query overlapping period: %w", classify(err))
+	}
+
+	return &rb, nil
+}
+
+func isExclusionViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == exclusionViolation
+}
+
+type sortColumn struct {
+	name string
+	cast string
+}
+
+// sortColumns сопоставляет полям сортировки колонки таблицы и приведение типа значения курсора.
+var sortColumns = map[string]sortColumn{
+	"":                {name: "id"},
+	SortByPrice:       {name: "price", cast: "::bigint"},
+	SortByStartDate:   {name: "start_date", cast: "::date"},
+	SortByServiceName: {name: "service_name", cast: "::text"},
+}
+
+// listFilter возвращает условие WHERE и его аргументы для фильтров списка подписок.
+func listFilter(params ListParams) (string, []any) {
+	where := "TRUE"
+	var args []any
+
+	if params.ServiceName != "" {
+		args = append(args, params.ServiceName)
+		where += fmt.Sprintf(" AND service_name = $%d", len(args))
+	}
+	if params.UserID != "" {
+		args = append(args, params.UserID)
+		where += fmt.Sprintf(" AND user_id = $%d::uuid", len(args))
+	}
+	if params.ActiveOn != nil {
+		args = append(args, *params.ActiveOn)
+		where += fmt.Sprintf(" AND start_date <= $%d::date AND (end_date IS NULL OR end_date >= $%d::date)", len(args), len(args))
+	}
+	if params.PriceMin != nil {
+		args = append(args, *params.PriceMin)
+		where += fmt.Sprintf(" AND price >= $%d", len(args))
+	}
+	if params.PriceMax != nil {
+		args = append(args, *params.PriceMax)
+		where += fmt.Sprintf(" AND price <= $%d", len(args))
+	}
+
+	return where, args
+}
+
+// listOrder дополняет условие списка позицией курсора и возвращает его вместе с порядком сортировки.
+func listOrder(params ListParams, where string, args []any) (string, string, []any, error) {
+	column, ok := sortColumns[params.Sort]
+	if !ok {
+		return "", "", nil, &Error{Kind: ErrInvalidInput, Reason: fmt.Sprintf("unknown sort field: %q", params.Sort)}
+	}
+
+	direction, cmp := "ASC", ">"
+	if params.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if params.Cursor != nil {
+		if params.Sort == "" {
+			args = append(args, params.Cursor.ID)
+			where += fmt.Sprintf(" AND id %s $%d", cmp, len(args))
+		} else {
+			value, err := CursorArg(params.Cursor)
+			if err != nil {
+				return "", "", nil, err
+			}
+			args = append(args, value, params.Cursor.ID)
+			where += fmt.Sprintf(" AND (%s, id) %s ($%d%s, $%d)", column.name, cmp, len(args)-1, column.cast, len(args))
+		}
+	}
+
+	order := "id " + direction
+	if params.Sort != "" {
+		order = column.name + " " + direction + ", " + order
+	}
+
+	return where, order, args, nil
+}
+
 func rollback(tx *sql.Tx, op string) {
 	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
 		slog.Error("Failed to rollback tx", slog.String("op", op), slog.Any("error", err))
 	}
 }
+
+// DB возвращает пул соединений, например для метрик пула.
+func (s *Storage) DB() *sql.DB {
+	return s.db
+}
@@ -0,0 +1,13 @@
+package postgre_test
+
+import (
+	"testing"
+
+	"gotest_23.07.25/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		return newStorage(t)
+	})
+}
@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gotest_23.07.25/internal/postgre"
+)
+
+// CreateAPIKey сохраняет ключ с хешем hash. Области доступа хранятся одной строкой через пробел.
+// Семантика совпадает с postgre.Storage.CreateAPIKey.
+func (s *Storage) CreateAPIKey(ctx context.Context, key postgre.APIKey, hash string) (*postgre.APIKey, error) {
+	const op = "internal.sqlite.CreateAPIKey"
+	slog.Info("Start create api key tx", slog.String("op", op))
+
+	key.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)`,
+		key.Name, key.Prefix, hash, strings.Join(key.Scopes, " "), key.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to insert into table: %w", op, classify(err))
+	}
+
+	if key.ID, err = res.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("%s: failed to get id: %w", op, classify(err))
+	}
+
+	slog.Info("Create api key done successfully", slog.String("op", op), slog.Int64("id", key.ID))
+	return &key, nil
+}
+
+// APIKeyByHash возвращает действующий (не отозванный) ключ по хешу или postgre.ErrRecordNotFound.
+func (s *Storage) APIKeyByHash(ctx context.Context, hash string) (*postgre.APIKey, error) {
+	const op = "internal.sqlite.APIKeyByHash"
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `
+		SELECT id, name, prefix, scopes, created_at, revoked_at
+		FROM api_keys
+		WHERE key_hash = ?1 AND revoked_at IS NULL`, hash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, postgre.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to query row: %w", op, classify(err))
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys возвращает все ключи, включая отозванные, в порядке создания.
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]postgre.APIKey, error) {
+	const op = "internal.sqlite.ListAPIKeys"
+	slog.Info("Start list api keys", slog.String("op", op))
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, prefix, scopes, created_at, revoked_at
+		FROM api_keys
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to query rows: %w", op, classify(err))
+	}
+	defer rows.Close()
+
+	keys := []postgre.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to scan row: %w", op, classify(err))
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows scan error: %w", op, classify(err))
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ. Для отсутствующего или уже отозванного ключа возвращает postgre.ErrRecordNotFound.
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "internal.sqlite.RevokeAPIKey"
+	slog.Info("Start revoke api key", slog.String("op", op), slog.Int64("id", id))
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = ?1
+		WHERE id = ?2 AND revoked_at IS NULL`, time.Now().UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return fmt.Errorf("%s: failed to update table: %w", op, classify(err))
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows: %w", op, classify(err))
+	}
+	if n == 0 {
+		return postgre.ErrRecordNotFound
+	}
+
+	slog.Info("Revoke api key done successfully", slog.String("op", op))
+	return nil
+}
+
+func scanAPIKey(row scanner) (*postgre.APIKey, error) {
+	var (
+		key       postgre.APIKey
+		scopes    string
+		createdAt string
+		revokedAt sql.NullString
+	)
+
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &createdAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+
+	var err error
+	if key.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+
+	if revokedAt.Valid {
+		t, err := time.Parse(time.RFC3339, revokedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse revoked_at: %w", err)
+		}
+		key.RevokedAt = &t
+	}
+
+	return &key, nil
+}
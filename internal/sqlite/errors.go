@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"gotest_23.07.25/internal/postgre"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// classify относит ошибку драйвера SQLite к одной из категорий хранилища (postgre.ErrNotFound и др.).
+// Ошибки, уже отнесенные к категории, и ошибки без категории возвращаются как есть.
+func classify(err error) error {
+	if err == nil || isClassified(err) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &postgre.Error{Kind: postgre.ErrNotFound, Reason: "record not found", Err: err}
+	}
+
+	var liteErr *sqlite.Error
+	if !errors.As(err, &liteErr) {
+		return err
+	}
+
+	switch code := liteErr.Code(); {
+	case code == sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return &postgre.Error{Kind: postgre.ErrConflict, Reason: "record conflicts with an existing one", Err: err}
+	case code&0xff == sqlite3.SQLITE_CONSTRAINT, code&0xff == sqlite3.SQLITE_MISMATCH:
+		return &postgre.Error{Kind: postgre.ErrInvalidInput, Reason: "record violates a table constraint", Err: err}
+	case code&0xff == sqlite3.SQLITE_BUSY, code&0xff == sqlite3.SQLITE_LOCKED, code&0xff == sqlite3.SQLITE_IOERR,
+		code&0xff == sqlite3.SQLITE_FULL, code&0xff == sqlite3.SQLITE_CANTOPEN:
+		return &postgre.Error{Kind: postgre.ErrUnavailable, Reason: "storage is temporarily unavailable", Err: err}
+	}
+
+	return err
+}
+
+func isClassified(err error) bool {
+	return errors.Is(err, postgre.ErrNotFound) || errors.Is(err, postgre.ErrConflict) ||
+		errors.Is(err, postgre.ErrInvalidInput) || errors.Is(err, postgre.ErrUnavailable)
+}
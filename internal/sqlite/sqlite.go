@@ -0,0 +1,732 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gotest_23.07.25/internal/lib/billing"
+	"gotest_23.07.25/internal/postgre"
+	_ "modernc.org/sqlite"
+)
+
+// dateLayout - формат, в котором даты хранятся в TEXT-колонках.
+const dateLayout = "2006-01-02"
+
+// rangeFilter отбирает подписки, пересекающиеся с периодом [?1, ?2], с фильтрами по имени сервиса (?3)
+// и ID пользователя (?4). Используется в aggregate для RangePrice и Report.
+const rangeFilter = `
+	start_date <= ?2
+	AND (end_date IS NULL OR end_date >= ?1)
+	AND (?3 = '' OR service_name = ?3)
+	AND (?4 = '' OR user_id = ?4)
+`
+
+type Storage struct {
+	db *sql.DB
+}
+
+// readOnly - параметры транзакций чтения. Они начинаются обычным BEGIN, а не BEGIN IMMEDIATE из _txlock,
+// и не занимают блокировку записи, пока, например, выгрузка идет потоком к клиенту.
+var readOnly = &sql.TxOptions{ReadOnly: true}
+
+// New открывает файл БД SQLite по указанному пути. Миграции применяются пакетом migrator.
+func New(path string) (*Storage, error) {
+	const op = "internal.sqlite.New"
+
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db.SetMaxOpenConns(10)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: failed to connect: %w", op, err)
+	}
+
+	return &Storage{db: db}, nil
+}
+
+// Create создает новую запись о подписке в таблице.
+// Возвращает id новой записи.
+func (s *Storage) Create(ctx context.Context, rb postgre.RequestFields) (int64, error) {
+	const op = "internal.sqlite.Create"
+	slog.Info("Start create tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	id, err := insert(ctx, tx, rb)
+	if err != nil {
+		if errors.Is(err, postgre.ErrSubscriptionExists) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Create done successfully", slog.String("op", op), slog.Int64("id", id))
+	return id, nil
+}
+
+// CreateBatch создает записи о подписках одной транзакцией и возвращает результат по каждой записи.
+// Семантика совпадает с postgre.Storage.CreateBatch.
+func (s *Storage) CreateBatch(ctx context.Context, rows []postgre.RequestFields, atomic bool) ([]postgre.BatchResult, error) {
+	const op = "internal.sqlite.CreateBatch"
+	slog.Info("Start create batch tx", slog.String("op", op), slog.Int("rows", len(rows)))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	results := make([]postgre.BatchResult, len(rows))
+	failed := false
+
+	for i, rb := range rows {
+		id, err := insert(ctx, tx, rb)
+		if err != nil {
+			if !postgre.IsRowError(err) {
+				return nil, fmt.Errorf("%s: row %d: %w", op, i+1, classify(err))
+			}
+			results[i].Err = err
+			failed = true
+			continue
+		}
+		results[i].ID = id
+	}
+
+	if atomic && failed {
+		for i := range results {
+			results[i].ID = 0
+		}
+		slog.Info("Create batch rolled back", slog.String("op", op))
+		return results, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Create batch done successfully", slog.String("op", op))
+	return results, nil
+}
+
+// Read возвращает информацию о подписке по ключу: id или имени сервиса и ID пользователя.
+func (s *Storage) Read(ctx context.Context, key postgre.SubscriptionKey) (*postgre.RequestFields, error) {
+	const op = "internal.sqlite.Read"
+	slog.Info("Start read tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, readOnly)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	where, args := keyFilter(key, 1)
+
+	rb, err := scanFields(tx.QueryRowContext(ctx, `
+		SELECT id, service_name, price, user_id, start_date, end_date, version
+		FROM subscriptions
+		WHERE `+where, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, postgre.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to query row: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Read done successfully", slog.String("op", op))
+	return rb, nil
+}
+
+// Update обновляет информацию о подписке в таблице и возвращает новую версию записи.
+// Если version не 0, запись обновляется только при совпадении версии, иначе возвращается postgre.ErrVersionMismatch.
+// Дата окончания раньше даты начала дает postgre.ErrInvalidPeriod, как в Patch.
+func (s *Storage) Update(ctx context.Context, key postgre.SubscriptionKey, rb postgre.RequestUpdateFields, version int64) (int64, error) {
+	const op = "internal.sqlite.Update"
+	slog.Info("Start update tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	where, args := keyFilter(key, 1)
+
+	var (
+		id          int64
+		serviceName string
+		userID      string
+		current     int64
+	)
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, service_name, user_id, version
+		FROM subscriptions
+		WHERE `+where, args...).Scan(&id, &serviceName, &userID, &current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, postgre.ErrRecordNotFound
+		}
+		return 0, fmt.Errorf("%s: failed to query row: %w", op, classify(err))
+	}
+
+	if version != 0 && version != current {
+		return 0, postgre.ErrVersionMismatch
+	}
+
+	if rb.EndDate != nil && rb.EndDate.Before(rb.StartDate) {
+		return 0, postgre.ErrInvalidPeriod
+	}
+
+	existing, err := findOverlap(ctx, tx, serviceName, userID, rb.StartDate, rb.EndDate, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if existing != nil {
+		return 0, &postgre.OverlapError{Existing: *existing}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE subscriptions
+		SET price = ?1, start_date = ?2, end_date = ?3, version = version + 1
+		WHERE id = ?4
+		RETURNING version
+	`, rb.Price, formatDate(rb.StartDate), formatDatePtr(rb.EndDate), id).Scan(&current)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to update table: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Update done successfully", slog.String("op", op))
+	return current, nil
+}
+
+// Patch частично изменяет подписку (см. postgre.PatchFields) и возвращает ее полную запись после изменения.
+// Версия проверяется так же, как в Update.
+func (s *Storage) Patch(ctx context.Context, key postgre.SubscriptionKey, patch postgre.PatchFields, version int64) (*postgre.RequestFields, error) {
+	const op = "internal.sqlite.Patch"
+	slog.Info("Start patch tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	where, args := keyFilter(key, 1)
+
+	rb, err := scanFields(tx.QueryRowContext(ctx, `
+		SELECT id, service_name, price, user_id, start_date, end_date, version
+		FROM subscriptions
+		WHERE `+where, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, postgre.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to query row: %w", op, classify(err))
+	}
+
+	if version != 0 && version != rb.Version {
+		return nil, postgre.ErrVersionMismatch
+	}
+
+	patched := patch.Apply(*rb)
+	if patched.EndDate != nil && formatDate(*patched.EndDate) < formatDate(patched.StartDate) {
+		return nil, postgre.ErrInvalidPeriod
+	}
+
+	existing, err := findOverlap(ctx, tx, patched.ServiceName, patched.UserId, patched.StartDate, patched.EndDate, patched.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+	if existing != nil {
+		return nil, &postgre.OverlapError{Existing: *existing}
+	}
+
+	rb, err = scanFields(tx.QueryRowContext(ctx, `
+		UPDATE subscriptions
+		SET price = ?1, start_date = ?2, end_date = ?3, version = version + 1
+		WHERE id = ?4
+		RETURNING id, service_name, price, user_id, start_date, end_date, version
+	`, patched.Price, formatDate(patched.StartDate), formatDatePtr(patched.EndDate), patched.ID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to update table: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Patch done successfully", slog.String("op", op))
+	return rb, nil
+}
+
+// Delete удаляет запись о подписке из таблицы.
+// Версия проверяется так же, как в Update.
+func (s *Storage) Delete(ctx context.Context, key postgre.SubscriptionKey, version int64) error {
+	const op = "internal.sqlite.Delete"
+	slog.Info("Start delete tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	where, args := keyFilter(key, 1)
+
+	var id, current int64
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, version
+		FROM subscriptions
+		WHERE `+where, args...).Scan(&id, &current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return postgre.ErrRecordNotFound
+		}
+		return fmt.Errorf("%s: failed to query row: %w", op, classify(err))
+	}
+
+	if version != 0 && version != current {
+		return postgre.ErrVersionMismatch
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE id = ?1`, id); err != nil {
+		return fmt.Errorf("%s: failed to delete from table: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Delete done successfully", slog.String("op", op))
+	return nil
+}
+
+// List возвращает страницу списка подписок с фильтрами, сортировкой и курсором из params.
+// Для постраничного обхода используется keyset-пагинация по паре (поле сортировки, id).
+func (s *Storage) List(ctx context.Context, params postgre.ListParams) (*postgre.ListPage, error) {
+	const op = "internal.sqlite.List"
+	slog.Info("Start list tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, readOnly)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	where, args := listFilter(params)
+
+	page := &postgre.ListPage{Subscriptions: []postgre.RequestFields{}}
+
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM subscriptions WHERE `+where, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("%s: failed to count rows: %w", op, classify(err))
+	}
+
+	where, order, args, err := listOrder(params, where, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+
+	limit := params.PageSize()
+	args = append(args, limit+1)
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, service_name, price, user_id, start_date, end_date, version
+		FROM subscriptions
+		WHERE %s
+		ORDER BY %s
+		LIMIT ?%d
+	`, where, order, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to query rows: %w", op, classify(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rb, err := scanFields(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to scan row: %w", op, classify(err))
+		}
+		page.Subscriptions = append(page.Subscriptions, *rb)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows scan error: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	if len(page.Subscriptions) > limit {
+		page.Subscriptions = page.Subscriptions[:limit]
+		page.NextCursor = params.NextCursor(page.Subscriptions[limit-1])
+	}
+
+	slog.Info("List done successfully", slog.String("op", op))
+	return page, nil
+}
+
+// Export передает в fn подписки под фильтрами, сортировкой и курсором params по мере чтения из БД.
+// Семантика совпадает с postgre.Storage.Export.
+func (s *Storage) Export(ctx context.Context, params postgre.ListParams, fn func(postgre.RequestFields) error) error {
+	const op = "internal.sqlite.Export"
+	slog.Info("Start export tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, readOnly)
+	if err != nil {
+		return fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	where, args := listFilter(params)
+
+	where, order, args, err := listOrder(params, where, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, classify(err))
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, service_name, price, user_id, start_date, end_date, version
+		FROM subscriptions
+		WHERE %s
+		ORDER BY %s
+	`, where, order), args...)
+	if err != nil {
+		return fmt.Errorf("%s: failed to query rows: %w", op, classify(err))
+	}
+	defer rows.Close()
+
+	var count int
+	for rows.Next() {
+		rb, err := scanFields(rows)
+		if err != nil {
+			return fmt.Errorf("%s: failed to scan row: %w", op, classify(err))
+		}
+		if err := fn(*rb); err != nil {
+			return fmt.Errorf("%s: %w", op, classify(err))
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: rows scan error: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Export done successfully", slog.String("op", op), slog.Int("count", count))
+	return nil
+}
+
+// RangePrice возвращает общую стоимость подписок за указанный диапазон дат и по указанным имени сервиса и id пользователя.
+// Цена умножается на число оплачиваемых месяцев, см. billing.BilledMonths и billing.Aggregator.
+func (s *Storage) RangePrice(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string) (uint64, error) {
+	const op = "internal.sqlite.RangePrice"
+	slog.Info("Start range price tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, readOnly)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	aggregator, err := aggregate(ctx, tx, start_date, end_date, service_name, user_id, postgre.ReportGroupBy{})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Range price done successfully", slog.String("op", op))
+	return aggregator.Total(), nil
+}
+
+// Report возвращает расходы на подписки за период, сгруппированные по полям groupBy.
+// Подписки отбираются тем же фильтром, что и в RangePrice, группировка выполняется в billing.Aggregator.
+func (s *Storage) Report(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy postgre.ReportGroupBy) ([]postgre.ReportGroup, error) {
+	const op = "internal.sqlite.Report"
+	slog.Info("Start report tx", slog.String("op", op))
+
+	tx, err := s.db.BeginTx(ctx, readOnly)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to begin tx: %w", op, classify(err))
+	}
+	defer rollback(tx, op)
+
+	aggregator, err := aggregate(ctx, tx, start_date, end_date, service_name, user_id, groupBy)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, classify(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit: %w", op, classify(err))
+	}
+
+	slog.Info("Report done successfully", slog.String("op", op))
+	return aggregator.Groups(), nil
+}
+
+func (s *Storage) Close() error {
+	const op = "internal.sqlite.Close"
+	slog.Info("Start close db connection", slog.String("op", op))
+
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%s: failed to close db connection: %w", op, classify(err))
+	}
+
+	slog.Info("Close db connection done successfully", slog.String("op", op))
+	return nil
+}
+
+// keyFilter возвращает условие WHERE для ключа подписки; плейсхолдеры нумеруются с first.
+func keyFilter(key postgre.SubscriptionKey, first int) (string, []any) {
+	if key.ID != 0 {
+		return fmt.Sprintf("id = ?%d", first), []any{key.ID}
+	}
+	return fmt.Sprintf(`id = (
+		SELECT id FROM subscriptions
+		WHERE service_name = ?%d AND user_id = ?%d
+		ORDER BY start_date DESC
+		LIMIT 1
+	)`, first, first+1), []any{key.ServiceName, strings.ToLower(key.UserID)}
+}
+
+// insert добавляет запись о подписке в транзакции tx и возвращает ее id.
+// Если период пересекается с существующим, возвращает postgre.OverlapError.
+func insert(ctx context.Context, tx *sql.Tx, rb postgre.RequestFields) (int64, error) {
+	existing, err := findOverlap(ctx, tx, rb.ServiceName, rb.UserId, rb.StartDate, rb.EndDate, 0)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		slog.Info("Subsctibtion period overlaps", slog.String("service_name", rb.ServiceName), slog.String("user_id", rb.UserId), slog.Int64("existing_id", existing.ID))
+		return 0, &postgre.OverlapError{Existing: *existing}
+	}
+
+	var id int64
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date)
+		VALUES(?1, ?2, ?3, ?4, ?5)
+		RETURNING id
+	`, rb.ServiceName, rb.Price, strings.ToLower(rb.UserId), formatDate(rb.StartDate), formatDatePtr(rb.EndDate)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert into table: %w", classify(err))
+	}
+
+	return id, nil
+}
+
+// findOverlap возвращает период подписки пары (serviceName, userID), пересекающийся с [startDate, endDate],
+// не считая записи excludeID, или nil, если пересечений нет.
+func findOverlap(ctx context.Context, tx *sql.Tx, serviceName, userID string, startDate time.Time, endDate *time.Time, excludeID int64) (*postgre.RequestFields, error) {
+	rb, err := scanFields(tx.QueryRowContext(ctx, `
+		SELECT id, service_name, price, user_id, start_date, end_date, version
+		FROM subscriptions
+		WHERE service_name = ?1 AND user_id = ?2 AND id <> ?3
+			AND (?5 IS NULL OR start_date <= ?5)
+			AND (end_date IS NULL OR end_date >= ?4)
+		ORDER BY start_date
+		LIMIT 1
+	`, serviceName, strings.ToLower(userID), excludeID, formatDate(startDate), formatDatePtr(endDate)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query overlapping period: %w", classify(err))
+	}
+
+	return rb, nil
+}
+
+// aggregate отбирает подписки, пересекающиеся с периодом, и учитывает их в billing.Aggregator.
+func aggregate(ctx context.Context, tx *sql.Tx, start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy postgre.ReportGroupBy) (*billing.Aggregator, error) {
+	from, to := formatDate(start_date), formatDate(end_date)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, service_name, price, user_id, start_date, end_date, version
+		FROM subscriptions
+		WHERE `+rangeFilter,
+		from, to, service_name, strings.ToLower(user_id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rows: %w", classify(err))
+	}
+	defer rows.Close()
+
+	fromDate, _ := time.Parse(dateLayout, from)
+	toDate, _ := time.Parse(dateLayout, to)
+	aggregator := billing.NewAggregator(groupBy, fromDate, toDate)
+
+	for rows.Next() {
+		rb, err := scanFields(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", classify(err))
+		}
+		aggregator.Add(*rb)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows scan error: %w", classify(err))
+	}
+
+	return aggregator, nil
+}
+
+// sortColumns сопоставляет полям сортировки колонки таблицы.
+var sortColumns = map[string]string{
+	"":                        "id",
+	postgre.SortByPrice:       "price",
+	postgre.SortByStartDate:   "start_date",
+	postgre.SortByServiceName: "service_name",
+}
+
+// listFilter возвращает условие WHERE и его аргументы для фильтров списка подписок.
+func listFilter(params postgre.ListParams) (string, []any) {
+	where := "1 = 1"
+	var args []any
+
+	if params.ServiceName != "" {
+		args = append(args, params.ServiceName)
+		where += fmt.Sprintf(" AND service_name = ?%d", len(args))
+	}
+	if params.UserID != "" {
+		args = append(args, strings.ToLower(params.UserID))
+		where += fmt.Sprintf(" AND user_id = ?%d", len(args))
+	}
+	if params.ActiveOn != nil {
+		args = append(args, formatDate(*params.ActiveOn))
+		where += fmt.Sprintf(" AND start_date <= ?%d AND (end_date IS NULL OR end_date >= ?%d)", len(args), len(args))
+	}
+	if params.PriceMin != nil {
+		args = append(args, *params.PriceMin)
+		where += fmt.Sprintf(" AND price >= ?%d", len(args))
+	}
+	if params.PriceMax != nil {
+		args = append(args, *params.PriceMax)
+		where += fmt.Sprintf(" AND price <= ?%d", len(args))
+	}
+
+	return where, args
+}
+
+// listOrder дополняет условие списка позицией курсора и возвращает его вместе с порядком сортировки.
+func listOrder(params postgre.ListParams, where string, args []any) (string, string, []any, error) {
+	column, ok := sortColumns[params.Sort]
+	if !ok {
+		return "", "", nil, &postgre.Error{Kind: postgre.ErrInvalidInput, Reason: fmt.Sprintf("unknown sort field: %q", params.Sort)}
+	}
+
+	direction, cmp := "ASC", ">"
+	if params.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if params.Cursor != nil {
+		if params.Sort == "" {
+			args = append(args, params.Cursor.ID)
+			where += fmt.Sprintf(" AND id %s ?%d", cmp, len(args))
+		} else {
+			value, err := postgre.CursorArg(params.Cursor)
+			if err != nil {
+				return "", "", nil, err
+			}
+			args = append(args, value, params.Cursor.ID)
+			where += fmt.Sprintf(" AND (%s, id) %s (?%d, ?%d)", column, cmp, len(args)-1, len(args))
+		}
+	}
+
+	order := "id " + direction
+	if params.Sort != "" {
+		order = column + " " + direction + ", " + order
+	}
+
+	return where, order, args, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanFields читает строку подписки (id, service_name, price, user_id, start_date, end_date, version),
+// разбирая даты из TEXT-колонок.
+func scanFields(row scanner) (*postgre.RequestFields, error) {
+	var (
+		rb        postgre.RequestFields
+		startDate string
+		endDate   sql.NullString
+	)
+
+	if err := row.Scan(&rb.ID, &rb.ServiceName, &rb.Price, &rb.UserId, &startDate, &endDate, &rb.Version); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if rb.StartDate, err = time.Parse(dateLayout, startDate); err != nil {
+		return nil, fmt.Errorf("failed to parse start_date: %w", err)
+	}
+
+	if endDate.Valid {
+		t, err := time.Parse(dateLayout, endDate.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse end_date: %w", err)
+		}
+		rb.EndDate = &t
+	}
+
+	return &rb, nil
+}
+
+func formatDate(t time.Time) string {
+	return t.UTC().Format(dateLayout)
+}
+
+func formatDatePtr(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	date := formatDate(*t)
+	return &date
+}
+
+func rollback(tx *sql.Tx, op string) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		slog.Error("Failed to rollback tx", slog.String("op", op), slog.Any("error", err))
+	}
+}
+
+// DB возвращает пул соединений, например для метрик пула.
+func (s *Storage) DB() *sql.DB {
+	return s.db
+}
@@ -0,0 +1,41 @@
+package sqlite_test
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"gotest_23.07.25/internal/config"
+	"gotest_23.07.25/internal/migrator"
+	"gotest_23.07.25/internal/sqlite"
+	"gotest_23.07.25/internal/storage/storagetest"
+)
+
+// newStorage создает БД во временном каталоге теста и применяет к ней миграции.
+func newStorage(t *testing.T) storagetest.Storage {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "subscriptions.db")
+
+	m, err := migrator.New(slog.New(slog.NewTextHandler(io.Discard, nil)), config.DriverSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(0); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	s, err := sqlite.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, newStorage)
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gotest_23.07.25/internal/metrics"
+	"gotest_23.07.25/internal/postgre"
+)
+
+// metricsStorage записывает длительность и ошибки каждой операции хранилища.
+type metricsStorage struct {
+	Storage
+	m *metrics.Metrics
+}
+
+// withMetrics возвращает хранилище, операции которого попадают в метрики m. При m == nil возвращает s.
+func withMetrics(s Storage, m *metrics.Metrics) Storage {
+	if m == nil {
+		return s
+	}
+	return &metricsStorage{Storage: s, m: m}
+}
+
+// observe записывает длительность операции op, начатой в start, и ее ошибку.
+func (s *metricsStorage) observe(op string, start time.Time, err error) {
+	s.m.StorageDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		s.m.StorageErrors.WithLabelValues(op, errorKind(err)).Inc()
+	}
+}
+
+// errorKind возвращает вид ошибки хранилища для метки метрики.
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, postgre.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, postgre.ErrConflict):
+		return "conflict"
+	case errors.Is(err, postgre.ErrInvalidInput):
+		return "invalid_input"
+	case errors.Is(err, postgre.ErrUnavailable):
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
+
+func (s *metricsStorage) Create(ctx context.Context, rb postgre.RequestFields) (id int64, err error) {
+	defer func(start time.Time) { s.observe("Create", start, err) }(time.Now())
+	return s.Storage.Create(ctx, rb)
+}
+
+func (s *metricsStorage) CreateBatch(ctx context.Context, rows []postgre.RequestFields, atomic bool) (res []postgre.BatchResult, err error) {
+	defer func(start time.Time) { s.observe("CreateBatch", start, err) }(time.Now())
+	return s.Storage.CreateBatch(ctx, rows, atomic)
+}
+
+func (s *metricsStorage) Read(ctx context.Context, key postgre.SubscriptionKey) (rb *postgre.RequestFields, err error) {
+	defer func(start time.Time) { s.observe("Read", start, err) }(time.Now())
+	return s.Storage.Read(ctx, key)
+}
+
+func (s *metricsStorage) Update(ctx context.Context, key postgre.SubscriptionKey, rb postgre.RequestUpdateFields, version int64) (newVersion int64, err error) {
+	defer func(start time.Time) { s.observe("Update", start, err) }(time.Now())
+	return s.Storage.Update(ctx, key, rb, version)
+}
+
+func (s *metricsStorage) Patch(ctx context.Context, key postgre.SubscriptionKey, patch postgre.PatchFields, version int64) (rb *postgre.RequestFields, err error) {
+	defer func(start time.Time) { s.observe("Patch", start, err) }(time.Now())
+	return s.Storage.Patch(ctx, key, patch, version)
+}
+
+func (s *metricsStorage) Delete(ctx context.Context, key postgre.SubscriptionKey, version int64) (err error) {
+	defer func(start time.Time) { s.observe("Delete", start, err) }(time.Now())
+	return s.Storage.Delete(ctx, key, version)
+}
+
+func (s *metricsStorage) List(ctx context.Context, params postgre.ListParams) (page *postgre.ListPage, err error) {
+	defer func(start time.Time) { s.observe("List", start, err) }(time.Now())
+	return s.Storage.List(ctx, params)
+}
+
+func (s *metricsStorage) Export(ctx context.Context, params postgre.ListParams, fn func(postgre.RequestFields) error) (err error) {
+	defer func(start time.Time) { s.observe("Export", start, err) }(time.Now())
+	return s.Storage.Export(ctx, params, fn)
+}
+
+func (s *metricsStorage) RangePrice(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string) (price uint64, err error) {
+	defer func(start time.Time) { s.observe("RangePrice", start, err) }(time.Now())
+	return s.Storage.RangePrice(ctx, start_date, end_date, service_name, user_id)
+}
+
+func (s *metricsStorage) Report(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy postgre.ReportGroupBy) (groups []postgre.ReportGroup, err error) {
+	defer func(start time.Time) { s.observe("Report", start, err) }(time.Now())
+	return s.Storage.Report(ctx, start_date, end_date, service_name, user_id, groupBy)
+}
+
+func (s *metricsStorage) CreateAPIKey(ctx context.Context, key postgre.APIKey, hash string) (created *postgre.APIKey, err error) {
+	defer func(start time.Time) { s.observe("CreateAPIKey", start, err) }(time.Now())
+	return s.Storage.CreateAPIKey(ctx, key, hash)
+}
+
+func (s *metricsStorage) APIKeyByHash(ctx context.Context, hash string) (key *postgre.APIKey, err error) {
+	defer func(start time.Time) { s.observe("APIKeyByHash", start, err) }(time.Now())
+	return s.Storage.APIKeyByHash(ctx, hash)
+}
+
+func (s *metricsStorage) ListAPIKeys(ctx context.Context) (keys []postgre.APIKey, err error) {
+	defer func(start time.Time) { s.observe("ListAPIKeys", start, err) }(time.Now())
+	return s.Storage.ListAPIKeys(ctx)
+}
+
+func (s *metricsStorage) RevokeAPIKey(ctx context.Context, id int64) (err error) {
+	defer func(start time.Time) { s.observe("RevokeAPIKey", start, err) }(time.Now())
+	return s.Storage.RevokeAPIKey(ctx, id)
+}
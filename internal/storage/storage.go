@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+
+	"gotest_23.07.25/internal/config"
+	"gotest_23.07.25/internal/health"
+	"gotest_23.07.25/internal/http-server/handlers"
+	"gotest_23.07.25/internal/http-server/middlewares/auth"
+	"gotest_23.07.25/internal/memory"
+	"gotest_23.07.25/internal/metrics"
+	"gotest_23.07.25/internal/migrator"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/sqlite"
+	"gotest_23.07.25/migrations"
+)
+
+// storage backends:
+const (
+	BackendSQL    = "sql"
+	BackendMemory = "memory"
+)
+
+// Storage объединяет интерфейсы, необходимые хендлерам, и закрытие хранилища.
+type Storage interface {
+	handlers.Create
+	handlers.CreateBatch
+	handlers.Read
+	handlers.Update
+	handlers.Patch
+	handlers.Delete
+	handlers.List
+	handlers.Export
+	handlers.RangePrice
+	handlers.Report
+	handlers.IssueKey
+	handlers.ListKeys
+	handlers.RevokeKey
+	auth.KeyStore
+	Close() error
+}
+
+// dbProvider - хранилище на database/sql, отдающее пул соединений.
+type dbProvider interface {
+	DB() *sql.DB
+}
+
+// New создает хранилище, выбранное ключом storage.backend в конфиге.
+// Операции хранилища ограничены временем storage.query_timeout, выгрузка - storage.export_timeout.
+// Если m не nil, в m регистрируются метрики операций хранилища, пула соединений и бизнес-метрики.
+// При включенной трассировке каждая операция записывается в дочерний спан запроса.
+// Если h не nil, для sql-хранилища в h добавляются проверки готовности БД.
+func New(cfg *config.Config, m *metrics.Metrics, h *health.Health) (Storage, error) {
+	const op = "internal.storage.New"
+
+	var (
+		storage Storage
+		err     error
+	)
+
+	switch cfg.Storage.Backend {
+	case BackendSQL:
+		storage, err = newSQL(cfg)
+	case BackendMemory:
+		storage = memory.New()
+	default:
+		err = fmt.Errorf("%s: unknown storage backend: %q", op, cfg.Storage.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p, ok := storage.(dbProvider); ok {
+		if m != nil {
+			m.Registry.MustRegister(collectors.NewDBStatsCollector(p.DB(), cfg.StorageLink.SQLDriver))
+		}
+		if h != nil {
+			if err := addHealthChecks(cfg, h, p.DB()); err != nil {
+				storage.Close()
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	storage = withTimeout(storage, cfg.Storage.QueryTimeout, cfg.Storage.ExportTimeout)
+
+	if m != nil {
+		m.Registry.MustRegister(metrics.NewBusinessCollector(slog.Default(), storage))
+	}
+
+	if cfg.Tracing.Enabled {
+		storage = withTracing(storage, dbSystem(cfg))
+	}
+
+	return withMetrics(storage, m), nil
+}
+
+// addHealthChecks добавляет в h проверки готовности БД с порогами из секции health конфига.
+// Ожидаемая версия схемы - последняя миграция, встроенная в бинарник для выбранного драйвера.
+func addHealthChecks(cfg *config.Config, h *health.Health, db *sql.DB) error {
+	const op = "internal.storage.addHealthChecks"
+
+	dir := migrations.DirPostgres
+	if cfg.StorageLink.SQLDriver == config.DriverSQLite {
+		dir = migrations.DirSQLite
+	}
+
+	version, err := migrations.Latest(dir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	h.AddDB(db, health.DBOptions{
+		MaxPingLatency:    cfg.Health.MaxPingLatency,
+		MaxPoolSaturation: cfg.Health.MaxPoolSaturation,
+		MigrationVersion:  version,
+	})
+
+	return nil
+}
+
+// dbSystem возвращает атрибут db.system.name для выбранного хранилища.
+func dbSystem(cfg *config.Config) attribute.KeyValue {
+	switch {
+	case cfg.Storage.Backend == BackendMemory:
+		return semconv.DBSystemNameKey.String(BackendMemory)
+	case cfg.StorageLink.SQLDriver == config.DriverSQLite:
+		return semconv.DBSystemNameSQLite
+	default:
+		return semconv.DBSystemNamePostgreSQL
+	}
+}
+
+// newSQL создает sql-хранилище для драйвера, указанного в storage_link.sql_driver.
+// При storage.auto_migrate к БД предварительно применяются все новые миграции.
+func newSQL(cfg *config.Config) (Storage, error) {
+	const op = "internal.storage.newSQL"
+
+	if cfg.Storage.AutoMigrate {
+		if err := migrateUp(cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	switch cfg.StorageLink.SQLDriver {
+	case config.DriverPostgres:
+		storage, err := postgre.New(config.GetStorageLink(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return storage, nil
+	case config.DriverSQLite:
+		storage, err := sqlite.New(config.GetStorageLink(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return storage, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown sql driver: %q", op, cfg.StorageLink.SQLDriver)
+	}
+}
+
+// migrateUp применяет к БД из конфига все новые миграции.
+func migrateUp(cfg *config.Config) error {
+	const op = "internal.storage.migrateUp"
+
+	m, err := migrator.New(slog.Default(), cfg.StorageLink.SQLDriver, config.GetStorageLink(cfg))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer m.Close()
+
+	if err := m.Up(0); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,347 @@
+// Package storagetest - общие тесты хранилищ подписок. Каждая реализация (memory, sqlite, postgre)
+// прогоняет их, чтобы поведение хранилищ совпадало.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"gotest_23.07.25/internal/postgre"
+)
+
+// Storage - операции хранилища, которые проверяют тесты.
+type Storage interface {
+	Create(ctx context.Context, rb postgre.RequestFields) (int64, error)
+	CreateBatch(ctx context.Context, rows []postgre.RequestFields, atomic bool) ([]postgre.BatchResult, error)
+	Read(ctx context.Context, key postgre.SubscriptionKey) (*postgre.RequestFields, error)
+	Update(ctx context.Context, key postgre.SubscriptionKey, rb postgre.RequestUpdateFields, version int64) (int64, error)
+	Delete(ctx context.Context, key postgre.SubscriptionKey, version int64) error
+	List(ctx context.Context, params postgre.ListParams) (*postgre.ListPage, error)
+	RangePrice(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string) (uint64, error)
+}
+
+// test users:
+const (
+	userA = "11111111-1111-1111-1111-111111111111"
+	userB = "22222222-2222-2222-2222-222222222222"
+)
+
+// Run прогоняет общие тесты. open возвращает пустое хранилище для каждого теста.
+func Run(t *testing.T, open func(t *testing.T) Storage) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s Storage)
+	}{
+		{"Overlap", testOverlap},
+		{"LatestPeriod", testLatestPeriod},
+		{"NotFound", testNotFound},
+		{"Version", testVersion},
+		{"InvalidPeriod", testInvalidPeriod},
+		{"BatchAtomic", testBatchAtomic},
+		{"BatchPerRow", testBatchPerRow},
+		{"ListCursor", testListCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+
+	t.Run("RangePrice", func(t *testing.T) {
+		testRangePrice(t, open)
+	})
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func datePtr(s string) *time.Time {
+	t := date(s)
+	return &t
+}
+
+func subscription(service, user string, price uint16, start string, end *time.Time) postgre.RequestFields {
+	return postgre.RequestFields{ServiceName: service, UserId: user, Price: price, StartDate: date(start), EndDate: end}
+}
+
+func mustCreate(t *testing.T, s Storage, rb postgre.RequestFields) int64 {
+	t.Helper()
+
+	id, err := s.Create(context.Background(), rb)
+	if err != nil {
+		t.Fatalf("Create(%+v): %v", rb, err)
+	}
+	return id
+}
+
+func testOverlap(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := mustCreate(t, s, subscription("Netflix", userA, 100, "2025-01-01", datePtr("2025-03-31")))
+
+	_, err := s.Create(ctx, subscription("Netflix", userA, 200, "2025-03-31", nil))
+	if !errors.Is(err, postgre.ErrSubscriptionExists) || !errors.Is(err, postgre.ErrConflict) {
+		t.Fatalf("Create overlapping period: err = %v, want ErrSubscriptionExists", err)
+	}
+	var overlap *postgre.OverlapError
+	if !errors.As(err, &overlap) || overlap.Existing.ID != id {
+		t.Errorf("Create overlapping period: err = %v, want OverlapError with id %d", err, id)
+	}
+
+	// Другая пара и соседний период не пересекаются.
+	mustCreate(t, s, subscription("Netflix", userB, 100, "2025-02-01", nil))
+	mustCreate(t, s, subscription("Spotify", userA, 100, "2025-02-01", nil))
+	mustCreate(t, s, subscription("Netflix", userA, 200, "2025-04-01", nil))
+}
+
+func testLatestPeriod(t *testing.T, s Storage) {
+	mustCreate(t, s, subscription("Netflix", userA, 100, "2025-01-01", datePtr("2025-01-31")))
+	latest := mustCreate(t, s, subscription("Netflix", userA, 300, "2025-06-01", nil))
+	mustCreate(t, s, subscription("Netflix", userA, 200, "2025-03-01", datePtr("2025-03-31")))
+
+	rb, err := s.Read(context.Background(), postgre.SubscriptionKey{ServiceName: "Netflix", UserID: userA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rb.ID != latest || rb.Price != 300 || !rb.StartDate.Equal(date("2025-06-01")) || rb.EndDate != nil {
+		t.Errorf("Read by service and user = %+v, want period %d from 2025-06-01", rb, latest)
+	}
+}
+
+func testNotFound(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	for _, key := range []postgre.SubscriptionKey{
+		{ID: 42},
+		{ServiceName: "Netflix", UserID: userA},
+	} {
+		if _, err := s.Read(ctx, key); !errors.Is(err, postgre.ErrNotFound) {
+			t.Errorf("Read(%+v): err = %v, want ErrNotFound", key, err)
+		}
+		if err := s.Delete(ctx, key, 0); !errors.Is(err, postgre.ErrNotFound) {
+			t.Errorf("Delete(%+v): err = %v, want ErrNotFound", key, err)
+		}
+	}
+}
+
+func testVersion(t *testing.T, s Storage) {
+	ctx := context.Background()
+	key := postgre.SubscriptionKey{ID: mustCreate(t, s, subscription("Netflix", userA, 100, "2025-01-01", nil))}
+
+	rb, err := s.Read(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rb.Version != 1 {
+		t.Fatalf("new record version = %d, want 1", rb.Version)
+	}
+
+	update := postgre.RequestUpdateFields{Price: 150, StartDate: date("2025-01-01")}
+	version, err := s.Update(ctx, key, update, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 2 {
+		t.Errorf("Update version = %d, want 2", version)
+	}
+
+	if _, err := s.Update(ctx, key, update, 1); !errors.Is(err, postgre.ErrVersionMismatch) {
+		t.Errorf("Update with stale version: err = %v, want ErrVersionMismatch", err)
+	}
+	if err := s.Delete(ctx, key, 1); !errors.Is(err, postgre.ErrVersionMismatch) {
+		t.Errorf("Delete with stale version: err = %v, want ErrVersionMismatch", err)
+	}
+
+	// Версия 0 - без проверки.
+	if _, err := s.Update(ctx, key, update, 0); err != nil {
+		t.Errorf("Update without version: %v", err)
+	}
+
+	if err := s.Delete(ctx, key, 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read(ctx, key); !errors.Is(err, postgre.ErrNotFound) {
+		t.Errorf("Read deleted record: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testInvalidPeriod(t *testing.T, s Storage) {
+	_, err := s.Create(context.Background(), subscription("Netflix", userA, 100, "2025-03-01", datePtr("2025-02-01")))
+	if !errors.Is(err, postgre.ErrInvalidInput) {
+		t.Errorf("Create with end before start: err = %v, want ErrInvalidInput", err)
+	}
+
+	key := postgre.SubscriptionKey{ID: mustCreate(t, s, subscription("Netflix", userA, 100, "2025-01-01", nil))}
+	_, err = s.Update(context.Background(), key, postgre.RequestUpdateFields{Price: 100, StartDate: date("2025-03-01"), EndDate: datePtr("2025-02-01")}, 0)
+	if !errors.Is(err, postgre.ErrInvalidPeriod) {
+		t.Errorf("Update with end before start: err = %v, want ErrInvalidPeriod", err)
+	}
+}
+
+func testBatchAtomic(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	results, err := s.CreateBatch(ctx, []postgre.RequestFields{
+		subscription("Netflix", userA, 100, "2025-01-01", nil),
+		subscription("Spotify", userA, 50, "2025-01-01", nil),
+		subscription("Netflix", userA, 200, "2025-02-01", nil),
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 || !errors.Is(results[2].Err, postgre.ErrSubscriptionExists) {
+		t.Fatalf("CreateBatch results = %+v, want overlap in row 3", results)
+	}
+	for i, r := range results {
+		if r.ID != 0 {
+			t.Errorf("row %d: id = %d after rollback, want 0", i+1, r.ID)
+		}
+	}
+
+	page, err := s.List(ctx, postgre.ListParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 0 {
+		t.Errorf("records after rolled back batch = %d, want 0", page.Total)
+	}
+
+	// Номера записей после отката продолжаются, а записи создаются как обычно.
+	mustCreate(t, s, subscription("Netflix", userA, 100, "2025-01-01", nil))
+}
+
+func testBatchPerRow(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	results, err := s.CreateBatch(ctx, []postgre.RequestFields{
+		subscription("Netflix", userA, 100, "2025-01-01", nil),
+		subscription("Netflix", userA, 200, "2025-02-01", nil),
+		subscription("Spotify", userA, 50, "2025-01-01", nil),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results[0].ID == 0 || results[0].Err != nil ||
+		!errors.Is(results[1].Err, postgre.ErrSubscriptionExists) ||
+		results[2].ID == 0 || results[2].Err != nil {
+		t.Fatalf("CreateBatch results = %+v, want rows 1 and 3 created", results)
+	}
+
+	page, err := s.List(ctx, postgre.ListParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 {
+		t.Errorf("records after per-row batch = %d, want 2", page.Total)
+	}
+}
+
+func testListCursor(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	// Одинаковые цены проверяют, что курсор различает записи по id.
+	for i, price := range []uint16{300, 100, 200, 100, 500} {
+		mustCreate(t, s, subscription("Service", userA, price, date("2025-01-01").AddDate(i, 0, 0).Format(time.DateOnly), datePtr(date("2025-01-01").AddDate(i, 6, 0).Format(time.DateOnly))))
+	}
+
+	for _, desc := range []bool{false, true} {
+		params := postgre.ListParams{Limit: 2, Sort: postgre.SortByPrice, Desc: desc}
+
+		var prices []uint16
+		seen := make(map[int64]bool)
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatal("too many pages")
+			}
+
+			page, err := s.List(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != 5 {
+				t.Errorf("Total = %d, want 5", page.Total)
+			}
+			for _, rb := range page.Subscriptions {
+				if seen[rb.ID] {
+					t.Errorf("record %d returned twice", rb.ID)
+				}
+				seen[rb.ID] = true
+				prices = append(prices, rb.Price)
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			params.Cursor, err = postgre.DecodeListCursor(page.NextCursor, params.Sort, params.Desc)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := []uint16{100, 100, 200, 300, 500}
+		if desc {
+			want = []uint16{500, 300, 200, 100, 100}
+		}
+		if len(prices) != len(want) {
+			t.Fatalf("desc=%v: prices = %v, want %v", desc, prices, want)
+		}
+		for i := range want {
+			if prices[i] != want[i] {
+				t.Fatalf("desc=%v: prices = %v, want %v", desc, prices, want)
+			}
+		}
+	}
+}
+
+// reportFixture - подписки и ожидаемая сумма из testdata пакета billing.
+type reportFixture struct {
+	From          time.Time               `json:"from"`
+	To            time.Time               `json:"to"`
+	Subscriptions []postgre.RequestFields `json:"subscriptions"`
+	Total         uint64                  `json:"total"`
+}
+
+// testRangePrice сверяет RangePrice с суммами из testdata пакета billing, которые проверяются и на billing.Aggregator.
+func testRangePrice(t *testing.T, open func(t *testing.T) Storage) {
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Join(filepath.Dir(file), "..", "..", "lib", "billing", "testdata")
+
+	for _, name := range []string{"report.json", "same_month.json"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f reportFixture
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatal(err)
+			}
+
+			s := open(t)
+			for _, rb := range f.Subscriptions {
+				mustCreate(t, s, rb)
+			}
+
+			total, err := s.RangePrice(context.Background(), f.From, f.To, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != f.Total {
+				t.Errorf("RangePrice() = %d, want %d", total, f.Total)
+			}
+		})
+	}
+}
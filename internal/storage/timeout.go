@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"gotest_23.07.25/internal/postgre"
+)
+
+// timeoutStorage ограничивает каждую операцию хранилища временем timeout, а выгрузку Export - временем
+// exportTimeout: выгрузка идет потоком и читает всю таблицу, поэтому длится дольше одного запроса.
+// Хранилище не встраивается, чтобы новый метод интерфейса Storage не остался без ограничения времени.
+type timeoutStorage struct {
+	next          Storage
+	timeout       time.Duration
+	exportTimeout time.Duration
+}
+
+var _ Storage = (*timeoutStorage)(nil)
+
+// withTimeout возвращает хранилище, операции которого прерываются через timeout, а выгрузка - через exportTimeout.
+// Значение <= 0 снимает ограничение; если оба значения <= 0, возвращает s.
+func withTimeout(s Storage, timeout, exportTimeout time.Duration) Storage {
+	if timeout <= 0 && exportTimeout <= 0 {
+		return s
+	}
+	return &timeoutStorage{next: s, timeout: timeout, exportTimeout: exportTimeout}
+}
+
+// limit возвращает контекст, отменяемый через d; при d <= 0 - ctx без изменений.
+func limit(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+func (s *timeoutStorage) Create(ctx context.Context, rb postgre.RequestFields) (int64, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.Create(ctx, rb)
+}
+
+func (s *timeoutStorage) CreateBatch(ctx context.Context, rows []postgre.RequestFields, atomic bool) ([]postgre.BatchResult, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.CreateBatch(ctx, rows, atomic)
+}
+
+func (s *timeoutStorage) Read(ctx context.Context, key postgre.SubscriptionKey) (*postgre.RequestFields, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.Read(ctx, key)
+}
+
+func (s *timeoutStorage) Update(ctx context.Context, key postgre.SubscriptionKey, rb postgre.RequestUpdateFields, version int64) (int64, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.Update(ctx, key, rb, version)
+}
+
+func (s *timeoutStorage) Patch(ctx context.Context, key postgre.SubscriptionKey, patch postgre.PatchFields, version int64) (*postgre.RequestFields, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.Patch(ctx, key, patch, version)
+}
+
+func (s *timeoutStorage) Delete(ctx context.Context, key postgre.SubscriptionKey, version int64) error {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.Delete(ctx, key, version)
+}
+
+func (s *timeoutStorage) List(ctx context.Context, params postgre.ListParams) (*postgre.ListPage, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.List(ctx, params)
+}
+
+func (s *timeoutStorage) Export(ctx context.Context, params postgre.ListParams, fn func(postgre.RequestFields) error) error {
+	ctx, cancel := limit(ctx, s.exportTimeout)
+	defer cancel()
+	return s.next.Export(ctx, params, fn)
+}
+
+func (s *timeoutStorage) RangePrice(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string) (uint64, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.RangePrice(ctx, start_date, end_date, service_name, user_id)
+}
+
+func (s *timeoutStorage) Report(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy postgre.ReportGroupBy) ([]postgre.ReportGroup, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.Report(ctx, start_date, end_date, service_name, user_id, groupBy)
+}
+
+func (s *timeoutStorage) CreateAPIKey(ctx context.Context, key postgre.APIKey, hash string) (*postgre.APIKey, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.CreateAPIKey(ctx, key, hash)
+}
+
+func (s *timeoutStorage) APIKeyByHash(ctx context.Context, hash string) (*postgre.APIKey, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.APIKeyByHash(ctx, hash)
+}
+
+func (s *timeoutStorage) ListAPIKeys(ctx context.Context) ([]postgre.APIKey, error) {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.ListAPIKeys(ctx)
+}
+
+func (s *timeoutStorage) RevokeAPIKey(ctx context.Context, id int64) error {
+	ctx, cancel := limit(ctx, s.timeout)
+	defer cancel()
+	return s.next.RevokeAPIKey(ctx, id)
+}
+
+func (s *timeoutStorage) Close() error {
+	return s.next.Close()
+}
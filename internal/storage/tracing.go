@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/tracing"
+)
+
+// statement - имя SQL-запроса операции хранилища: вид запроса и таблица.
+type statement struct {
+	operation string
+	table     string
+}
+
+// statements - запросы, которые выполняют операции хранилища.
+// RangePrice и Report читают подписки через общий запрос billed с помесячной разбивкой.
+var statements = map[string]statement{
+	"Create":       {"INSERT", "subscriptions"},
+	"CreateBatch":  {"INSERT", "subscriptions"},
+	"Read":         {"SELECT", "subscriptions"},
+	"Update":       {"UPDATE", "subscriptions"},
+	"Patch":        {"UPDATE", "subscriptions"},
+	"Delete":       {"DELETE", "subscriptions"},
+	"List":         {"SELECT", "subscriptions"},
+	"Export":       {"SELECT", "subscriptions"},
+	"RangePrice":   {"SELECT", "billed"},
+	"Report":       {"SELECT", "billed"},
+	"CreateAPIKey": {"INSERT", "api_keys"},
+	"APIKeyByHash": {"SELECT", "api_keys"},
+	"ListAPIKeys":  {"SELECT", "api_keys"},
+	"RevokeAPIKey": {"UPDATE", "api_keys"},
+}
+
+// tracingStorage создает дочерний спан на каждую операцию хранилища.
+type tracingStorage struct {
+	Storage
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// withTracing возвращает хранилище, операции которого записываются в спаны. system - вид БД для атрибута db.system.name.
+func withTracing(s Storage, system attribute.KeyValue) Storage {
+	return &tracingStorage{Storage: s, system: system, tracer: tracing.Tracer()}
+}
+
+// start открывает спан операции op.
+func (s *tracingStorage) start(ctx context.Context, op string) (context.Context, trace.Span) {
+	st := statements[op]
+	return s.tracer.Start(ctx, "storage."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			s.system,
+			semconv.DBOperationName(st.operation),
+			semconv.DBCollectionName(st.table),
+			semconv.DBQuerySummary(st.operation+" "+st.table),
+		),
+	)
+}
+
+// end закрывает спан и отмечает в нем ошибку. Отсутствие записи не считается ошибкой спана.
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, postgre.ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, errorKind(err))
+	}
+	span.End()
+}
+
+func (s *tracingStorage) Create(ctx context.Context, rb postgre.RequestFields) (id int64, err error) {
+	ctx, span := s.start(ctx, "Create")
+	defer func() { end(span, err) }()
+	return s.Storage.Create(ctx, rb)
+}
+
+func (s *tracingStorage) CreateBatch(ctx context.Context, rows []postgre.RequestFields, atomic bool) (res []postgre.BatchResult, err error) {
+	ctx, span := s.start(ctx, "CreateBatch")
+	defer func() { end(span, err) }()
+	span.SetAttributes(attribute.Int("db.operation.batch.size", len(rows)))
+	return s.Storage.CreateBatch(ctx, rows, atomic)
+}
+
+func (s *tracingStorage) Read(ctx context.Context, key postgre.SubscriptionKey) (rb *postgre.RequestFields, err error) {
+	ctx, span := s.start(ctx, "Read")
+	defer func() { end(span, err) }()
+	return s.Storage.Read(ctx, key)
+}
+
+func (s *tracingStorage) Update(ctx context.Context, key postgre.SubscriptionKey, rb postgre.RequestUpdateFields, version int64) (newVersion int64, err error) {
+	ctx, span := s.start(ctx, "Update")
+	defer func() { end(span, err) }()
+	return s.Storage.Update(ctx, key, rb, version)
+}
+
+func (s *tracingStorage) Patch(ctx context.Context, key postgre.SubscriptionKey, patch postgre.PatchFields, version int64) (rb *postgre.RequestFields, err error) {
+	ctx, span := s.start(ctx, "Patch")
+	defer func() { end(span, err) }()
+	return s.Storage.Patch(ctx, key, patch, version)
+}
+
+func (s *tracingStorage) Delete(ctx context.Context, key postgre.SubscriptionKey, version int64) (err error) {
+	ctx, span := s.start(ctx, "Delete")
+	defer func() { end(span, err) }()
+	return s.Storage.Delete(ctx, key, version)
+}
+
+func (s *tracingStorage) List(ctx context.Context, params postgre.ListParams) (page *postgre.ListPage, err error) {
+	ctx, span := s.start(ctx, "List")
+	defer func() { end(span, err) }()
+	return s.Storage.List(ctx, params)
+}
+
+func (s *tracingStorage) Export(ctx context.Context, params postgre.ListParams, fn func(postgre.RequestFields) error) (err error) {
+	ctx, span := s.start(ctx, "Export")
+	defer func() { end(span, err) }()
+	return s.Storage.Export(ctx, params, fn)
+}
+
+func (s *tracingStorage) RangePrice(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string) (price uint64, err error) {
+	ctx, span := s.start(ctx, "RangePrice")
+	defer func() { end(span, err) }()
+	return s.Storage.RangePrice(ctx, start_date, end_date, service_name, user_id)
+}
+
+func (s *tracingStorage) Report(ctx context.Context, start_date time.Time, end_date time.Time, service_name string, user_id string, groupBy postgre.ReportGroupBy) (groups []postgre.ReportGroup, err error) {
+	ctx, span := s.start(ctx, "Report")
+	defer func() { end(span, err) }()
+	return s.Storage.Report(ctx, start_date, end_date, service_name, user_id, groupBy)
+}
+
+func (s *tracingStorage) CreateAPIKey(ctx context.Context, key postgre.APIKey, hash string) (created *postgre.APIKey, err error) {
+	ctx, span := s.start(ctx, "CreateAPIKey")
+	defer func() { end(span, err) }()
+	return s.Storage.CreateAPIKey(ctx, key, hash)
+}
+
+func (s *tracingStorage) APIKeyByHash(ctx context.Context, hash string) (key *postgre.APIKey, err error) {
+	ctx, span := s.start(ctx, "APIKeyByHash")
+	defer func() { end(span, err) }()
+	return s.Storage.APIKeyByHash(ctx, hash)
+}
+
+func (s *tracingStorage) ListAPIKeys(ctx context.Context) (keys []postgre.APIKey, err error) {
+	ctx, span := s.start(ctx, "ListAPIKeys")
+	defer func() { end(span, err) }()
+	return s.Storage.ListAPIKeys(ctx)
+}
+
+func (s *tracingStorage) RevokeAPIKey(ctx context.Context, id int64) (err error) {
+	ctx, span := s.start(ctx, "RevokeAPIKey")
+	defer func() { end(span, err) }()
+	return s.Storage.RevokeAPIKey(ctx, id)
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exporters:
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// instrumentationName - имя трейсера сервиса.
+const instrumentationName = "gotest_23.07.25"
+
+// Options - настройки экспорта трейсов.
+type Options struct {
+	ServiceName string
+	// Exporter - otlp, stdout или file.
+	Exporter string
+	// OTLPEndpoint - адрес коллектора OTLP/HTTP (host:port); пустой - из OTEL_EXPORTER_OTLP_ENDPOINT.
+	OTLPEndpoint string
+	OTLPInsecure bool
+	// File - файл для экспортера file; спаны дописываются в него построчно в JSON.
+	File string
+	// SampleRatio - доля трейсов, начатых сервисом; решение вызывающей стороны из traceparent соблюдается.
+	SampleRatio float64
+}
+
+// Setup настраивает глобальные TracerProvider и распространение контекста W3C traceparent.
+// Возвращенная функция выгружает оставшиеся спаны и закрывает экспортер.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	const op = "internal.tracing.Setup"
+
+	exporter, closer, err := newExporter(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to build resource: %w", op, err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	shutdown := func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}
+
+	return shutdown, nil
+}
+
+// newExporter создает экспортер, выбранный в opts. closer закрывает файл экспортера file.
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, io.Closer, error) {
+	switch opts.Exporter {
+	case ExporterOTLP:
+		var otlpOpts []otlptracehttp.Option
+		if opts.OTLPEndpoint != "" {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithEndpoint(opts.OTLPEndpoint))
+		}
+		if opts.OTLPInsecure {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, otlpOpts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		return exp, nil, nil
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exp, nil, nil
+	case ExporterFile:
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		return exp, f, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown exporter %q, expected one of: %s, %s, %s", opts.Exporter, ExporterOTLP, ExporterStdout, ExporterFile)
+	}
+}
+
+// Tracer возвращает трейсер сервиса. Пока Setup не вызван, спаны не записываются.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// LogAttr возвращает trace_id и span_id текущего спана для slog.
+// Без спана в контексте возвращает пустой атрибут, который slog не выводит.
+func LogAttr(ctx context.Context) slog.Attr {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return slog.Attr{}
+	}
+	return slog.Group("",
+		slog.String("trace_id", sc.TraceID().String()),
+		slog.String("span_id", sc.SpanID().String()),
+	)
+}
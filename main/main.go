@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,12 +18,43 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 	_ "gotest_23.07.25/docs"
 	"gotest_23.07.25/internal/config"
+	grpcserver "gotest_23.07.25/internal/grpc-server"
+	"gotest_23.07.25/internal/health"
 	"gotest_23.07.25/internal/http-server/handlers"
+	"gotest_23.07.25/internal/http-server/middlewares/auth"
+	"gotest_23.07.25/internal/http-server/middlewares/httpmetrics"
+	"gotest_23.07.25/internal/http-server/middlewares/httptrace"
+	"gotest_23.07.25/internal/http-server/middlewares/idempotency"
 	"gotest_23.07.25/internal/http-server/middlewares/logger"
+	"gotest_23.07.25/internal/http-server/middlewares/ratelimit"
+	"gotest_23.07.25/internal/http-server/response"
+	"gotest_23.07.25/internal/lib/apikey"
 	"gotest_23.07.25/internal/lib/slogpretty"
-	"gotest_23.07.25/internal/postgre"
+	"gotest_23.07.25/internal/lib/token"
+	"gotest_23.07.25/internal/metrics"
+	"gotest_23.07.25/internal/storage"
+	"gotest_23.07.25/internal/tracing"
 )
 
+// commands:
+const (
+	cmdServe   = "serve"
+	cmdMigrate = "migrate"
+)
+
+// usage - справка по командам сервиса.
+const usage = `usage: app [command]
+
+commands:
+  serve                       start the HTTP and gRPC servers (default)
+  migrate up [N]              apply all or N next migrations
+  migrate down [N]            roll back N last migrations (1 by default)
+  migrate version             print the applied schema version
+  migrate force V             set the schema version to V without running migrations
+  migrate create [-dir D] NAME
+                              create empty up and down migrations NAME in D and D/sqlite
+`
+
 // logger levels:
 const (
 	envLocal = "local"
@@ -30,16 +64,63 @@ const (
 
 // api methods addresses:
 const (
-	createSubscription = "/api/v1/subscriptions"                          // post
-	listSubscriptions  = "/api/v1/subscriptions"                          // get
-	readSubscription   = "/api/v1/subscriptions/{service_name}/{user_id}" // get
-	deleteSubscription = "/api/v1/subscriptions/{service_name}/{user_id}" // delete
-	updateSubscription = "/api/v1/subscriptions/{service_name}/{user_id}" // put
-	rangePrice         = "/api/v1/subscriptions/range-price"              // post
+	createSubscription  = "/api/v1/subscriptions"                          // post
+	batchSubscriptions  = "/api/v1/subscriptions:batch"                    // post
+	listSubscriptions   = "/api/v1/subscriptions"                          // get
+	exportSubscriptions = "/api/v1/subscriptions/export"                   // get
+	readSubscription    = "/api/v1/subscriptions/{service_name}/{user_id}" // get
+	deleteSubscription  = "/api/v1/subscriptions/{service_name}/{user_id}" // delete
+	updateSubscription  = "/api/v1/subscriptions/{service_name}/{user_id}" // put, patch
+	subscriptionByID    = "/api/v1/subscriptions/{id:[0-9]+}"              // get, put, patch, delete
+	rangePrice          = "/api/v1/subscriptions/range-price"              // post
+	spendingReport      = "/api/v1/subscriptions/report"                   // post
+	adminKeys           = "/api/v1/admin/keys"                             // post, get
+	adminKeyByID        = "/api/v1/admin/keys/{id:[0-9]+}"                 // delete
+	liveness            = "/healthz"                                       // get
+	readiness           = "/readyz"                                        // get
+)
 
+// route groups, for rate limits:
+const (
+	groupSubscriptionsRead  = "subscriptions_read"
+	groupSubscriptionsWrite = "subscriptions_write"
+	groupReports            = "reports"
+	groupAdmin              = "admin"
+	// groupAuth ограничивает по IP все запросы до проверки учетных данных,
+	// чтобы подбор ключей и запросы с неверными ключами тоже подчинялись лимитам.
+	groupAuth = "auth"
 )
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Ключ API с нужной областью доступа: subscriptions:read, subscriptions:write, reports:read или admin.
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в виде "Bearer <token>". Без роли администратора доступны только подписки пользователя из токена.
 func main() {
+	cmd, args := cmdServe, os.Args[1:]
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case cmdServe:
+		serve()
+	case cmdMigrate:
+		os.Exit(runMigrate(args))
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+}
+
+// loadConfig загружает config.env и конфиг и настраивает логгер по умолчанию.
+func loadConfig() (*config.Config, *slog.Logger) {
 	if err := godotenv.Load("config.env"); err != nil {
 		slog.Error("failed to load .env file", slog.String("error", err.Error()))
 		os.Exit(1)
@@ -50,37 +131,94 @@ func main() {
 
 	log := setupLogger(cfg.Env)
 	slog.SetDefault(log)
+
+	return cfg, log
+}
+
+// serve запускает HTTP-сервер и, если он включен, gRPC-сервер и работает до сигнала остановки.
+func serve() {
+	cfg, log := loadConfig()
 	slog.Info("Starting service", slog.String("env", cfg.Env))
 	slog.Debug("Debug messages are enabled")
 	slog.Error("Error messages are enabled")
 
-	storage, err := initStorage(cfg)
+	shutdownTracing, err := initTracing(cfg)
+	if err != nil {
+		os.Exit(1)
+	}
+	defer shutdownTracing()
+
+	metrics := initMetrics(cfg)
+	health := health.New(cfg.Health.Timeout)
+
+	storage, err := initStorage(cfg, metrics, health)
 	if err != nil {
 		os.Exit(1)
 	}
 	defer storage.Close()
 
-	router := initRouter(log)
-	initHandlers(log, router, storage)
+	authOpts, err := authOptions(cfg, storage)
+	if err != nil {
+		slog.Error("failed to init authentication", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	limits, err := rateLimits(cfg)
+	if err != nil {
+		slog.Error("failed to init rate limits", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	router := initRouter(cfg, log, metrics)
+	initHandlers(cfg, log, router, storage, authOpts, limits)
 
 	router.Get("/swagger/*", httpSwagger.WrapHandler)
+	if metrics != nil {
+		router.Handle(cfg.Metrics.Path, metrics.Handler())
+	}
+	router.Get(liveness, handlers.NewLiveness())
+	router.Get(readiness, handlers.NewReadiness(log, health))
 
-	if err := startServer(cfg, router, log); err != nil {
+	grpcSrv := initGRPCServer(cfg, log, storage, authOpts, limits, metrics)
+
+	if err := startServer(cfg, router, grpcSrv, log, health); err != nil {
 		slog.Error("failed to start server", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 }
 
-// startServer инициализирует старт сервера через горутину.
-func startServer(cfg *config.Config, router *chi.Mux, log *slog.Logger) error {
+// startServer инициализирует старт сервера через горутину. grpcSrv запускается рядом с HTTP-сервером, если он не nil.
+// При остановке сначала переводит /readyz и grpc.health.v1 в отказ и ждет health.drain_delay,
+// затем останавливает оба сервера одновременно.
+func startServer(cfg *config.Config, router *chi.Mux, grpcSrv *grpcserver.Server, log *slog.Logger, health *health.Health) error {
+	// Контексты запросов отменяются, если они не успели завершиться за время остановки сервера.
+	requestsCtx, cancelRequests := context.WithCancelCause(context.Background())
+	defer cancelRequests(nil)
+
 	srv := &http.Server{
-		Addr:    cfg.HTTPServer.Address,
-		Handler: router,
+		Addr:        cfg.HTTPServer.Address,
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return requestsCtx },
+	}
+
+	if grpcSrv != nil {
+		lis, err := net.Listen("tcp", cfg.GRPCServer.Address)
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			slog.Info("Starting gRPC server", slog.String("address", cfg.GRPCServer.Address))
+			if err := grpcSrv.Serve(lis); err != nil {
+				slog.Error("Failed to start gRPC server", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
 	}
 
 	go func() {
 		slog.Info("Starting HTTP server", slog.String("address", cfg.HTTPServer.Address))
-		if err := srv.ListenAndServe(); err != nil {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("Failed to start HTTP server", slog.String("error", err.Error()))
 			os.Exit(1)
 		}
@@ -90,24 +228,118 @@ func startServer(cfg *config.Config, router *chi.Mux, log *slog.Logger) error {
 	defer stop()
 
 	<-ctx.Done()
+	// Повторный сигнал во время остановки завершает процесс сразу.
+	stop()
+
+	health.Drain()
+	if grpcSrv != nil {
+		grpcSrv.Drain()
+	}
+	log.Info("readiness probe is failing, draining traffic", slog.Duration("drain_delay", cfg.Health.DrainDelay))
+	time.Sleep(cfg.Health.DrainDelay)
+
 	log.Info("shutting down server gracefully")
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// gRPC-сервер останавливается параллельно с HTTP-сервером; незавершенные вызовы прерываются по тому же таймауту.
+	grpcStopped := make(chan struct{})
+	go func() {
+		defer close(grpcStopped)
+		if grpcSrv == nil {
+			return
+		}
+		if err := grpcSrv.Shutdown(shutdownCtx); err != nil {
+			log.Warn("gRPC graceful shutdown timed out, in-flight calls canceled", slog.String("error", err.Error()))
+		}
+	}()
+
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Error("graceful shutdown failed", slog.String("error", err.Error()))
-		return err
-	} else {
-		log.Info("server stopped")
+		log.Warn("graceful shutdown timed out, canceling in-flight requests", slog.String("error", err.Error()))
+		cancelRequests(http.ErrServerClosed)
+
+		cancelCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := srv.Shutdown(cancelCtx); err != nil {
+			log.Error("graceful shutdown failed", slog.String("error", err.Error()))
+			return err
+		}
 	}
+	<-grpcStopped
+	log.Info("server stopped")
 
 	return nil
 }
 
-// initStorage инициализирует in-memory хранилище и возвращает указатель на него
-func initStorage(cfg *config.Config) (*postgre.Storage, error) {
-	slog.Info("Init storage started")
-	storage, err := postgre.New(config.GetStorageLink(cfg))
+// initGRPCServer создает gRPC-сервер с тем же хранилищем, аутентификацией и лимитами, что и HTTP API.
+// Возвращает nil, если gRPC-сервер выключен в конфиге.
+func initGRPCServer(cfg *config.Config, log *slog.Logger, storage storage.Storage, authOpts auth.Options, limits map[string]*ratelimit.Limiter, m *metrics.Metrics) *grpcserver.Server {
+	if !cfg.GRPCServer.Enabled {
+		slog.Info("gRPC server is disabled")
+		return nil
+	}
+
+	return grpcserver.New(log, storage, grpcserver.Options{
+		Auth:       authOpts,
+		Tracing:    cfg.Tracing.Enabled,
+		Reflection: cfg.GRPCServer.Reflection,
+		Limits: grpcserver.Limits{
+			Auth:    limits[groupAuth],
+			Read:    limits[groupSubscriptionsRead],
+			Write:   limits[groupSubscriptionsWrite],
+			Reports: limits[groupReports],
+		},
+		Metrics: m,
+	})
+}
+
+// initTracing настраивает экспорт трейсов OpenTelemetry, если трассировка включена в конфиге.
+// Возвращает функцию, выгружающую оставшиеся спаны при остановке сервиса.
+func initTracing(cfg *config.Config) (func(), error) {
+	if !cfg.Tracing.Enabled {
+		slog.Info("Tracing is disabled")
+		return func() {}, nil
+	}
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName:  cfg.Tracing.ServiceName,
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		File:         cfg.Tracing.File,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		slog.Error("failed to init tracing", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	slog.Info("Tracing enabled", slog.String("exporter", cfg.Tracing.Exporter))
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			slog.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}, nil
+}
+
+// initMetrics создает реестр метрик Prometheus, если метрики включены в конфиге
+func initMetrics(cfg *config.Config) *metrics.Metrics {
+	if !cfg.Metrics.Enabled {
+		slog.Info("Metrics are disabled")
+		return nil
+	}
+
+	slog.Info("Metrics enabled", slog.String("path", cfg.Metrics.Path))
+	return metrics.New()
+}
+
+// initStorage инициализирует хранилище, выбранное в конфиге (sql или in-memory), и возвращает его.
+// Проверки готовности БД добавляются в h.
+func initStorage(cfg *config.Config, m *metrics.Metrics, h *health.Health) (storage.Storage, error) {
+	slog.Info("Init storage started", slog.String("backend", cfg.Storage.Backend))
+	storage, err := storage.New(cfg, m, h)
 	if err != nil {
 		slog.Error("failed to init storage: %w", slog.String("error", err.Error()))
 		return nil, err
@@ -118,25 +350,162 @@ func initStorage(cfg *config.Config) (*postgre.Storage, error) {
 }
 
 // initHandlers инициализирует хендлеры для обработки запросов.
-func initHandlers(log *slog.Logger, router *chi.Mux, storage *postgre.Storage) {
+// POST-запросы поддерживают заголовок Idempotency-Key.
+// При включенной аутентификации каждый маршрут требует ключ API с нужной областью доступа.
+// limits - лимиты запросов по группам маршрутов.
+func initHandlers(cfg *config.Config, log *slog.Logger, router *chi.Mux, storage storage.Storage, authOpts auth.Options, limits map[string]*ratelimit.Limiter) {
 	slog.Info("Init handlers started")
-	router.Post(createSubscription, handlers.NewCreate(log, storage))
-	router.Get(listSubscriptions, handlers.NewList(log, storage))
-	router.Get(readSubscription, handlers.NewRead(log, storage))
-	router.Delete(deleteSubscription, handlers.NewDelete(log, storage))
-	router.Put(updateSubscription, handlers.NewUpdate(log, storage))
-	router.Post(rangePrice, handlers.NewRangePrice(log, storage))
+
+	authEnabled := authOpts.Enabled()
+	if !authEnabled {
+		log.Warn("authentication is disabled, all routes are public")
+	}
+
+	// scope возвращает middleware проверки области доступа или пропускает запрос, если аутентификация выключена.
+	scope := func(s string) func(http.Handler) http.Handler {
+		if !authEnabled {
+			return func(next http.Handler) http.Handler { return next }
+		}
+		return auth.Require(s)
+	}
+
+	// limit возвращает middleware лимитов группы или пропускает запрос, если для группы лимиты не заданы.
+	limit := func(group string) func(http.Handler) http.Handler {
+		if l, ok := limits[group]; ok {
+			return ratelimit.New(log, group, l)
+		}
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	router.Group(func(r chi.Router) {
+		r.Use(limit(groupAuth))
+		if authEnabled {
+			r.Use(auth.New(log, authOpts))
+		}
+
+		read := r.With(scope(apikey.ScopeSubscriptionsRead), limit(groupSubscriptionsRead))
+		write := r.With(scope(apikey.ScopeSubscriptionsWrite), limit(groupSubscriptionsWrite))
+		reports := r.With(scope(apikey.ScopeReportsRead), limit(groupReports))
+		admin := r.With(scope(apikey.ScopeAdmin), limit(groupAdmin))
+
+		idempotent := idempotency.New(log, idempotency.Options{
+			TTL:        cfg.Idempotency.TTL,
+			MaxEntries: cfg.Idempotency.MaxEntries,
+			MaxBytes:   cfg.Idempotency.MaxBytes,
+		})
+		write.With(idempotent).Post(createSubscription, handlers.NewCreate(log, storage))
+		write.With(idempotent).Post(batchSubscriptions, handlers.NewCreateBatch(log, storage))
+		read.Get(listSubscriptions, handlers.NewList(log, storage))
+		read.Get(exportSubscriptions, handlers.NewExport(log, storage))
+		read.Get(readSubscription, handlers.NewRead(log, storage))
+		write.Delete(deleteSubscription, handlers.NewDelete(log, storage))
+		write.Put(updateSubscription, handlers.NewUpdate(log, storage))
+		write.Patch(updateSubscription, handlers.NewPatch(log, storage))
+		read.Get(subscriptionByID, handlers.NewReadByID(log, storage))
+		write.Put(subscriptionByID, handlers.NewUpdateByID(log, storage))
+		write.Patch(subscriptionByID, handlers.NewPatchByID(log, storage))
+		write.Delete(subscriptionByID, handlers.NewDeleteByID(log, storage))
+		reports.With(idempotent).Post(rangePrice, handlers.NewRangePrice(log, storage))
+		reports.With(idempotent).Post(spendingReport, handlers.NewReport(log, storage))
+		admin.Post(adminKeys, handlers.NewIssueKey(log, storage))
+		admin.Get(adminKeys, handlers.NewListKeys(log, storage))
+		admin.Delete(adminKeyByID, handlers.NewRevokeKey(log, storage))
+	})
+
 	slog.Info("Handlers initialization successfully")
 }
 
-// initRouter инициализирует роутер и подключает middleware
-func initRouter(log *slog.Logger) *chi.Mux {
+// authOptions возвращает способы аутентификации, включенные в секциях auth и jwt конфига.
+// Ключи API без JWT и без ключа администратора не запускаются: выпустить первый ключ было бы нечем.
+func authOptions(cfg *config.Config, storage storage.Storage) (auth.Options, error) {
+	var opts auth.Options
+
+	if cfg.Auth.Enabled && !cfg.JWT.Enabled && cfg.Auth.AdminKey == "" {
+		return opts, errors.New("auth is enabled without jwt and admin key: set auth.admin_key or API_ADMIN_KEY")
+	}
+
+	if cfg.Auth.Enabled {
+		opts.Keys = storage
+		opts.AdminKey = cfg.Auth.AdminKey
+	}
+
+	if cfg.JWT.Enabled {
+		verifier, err := token.NewVerifier(token.Options{
+			Secret:    cfg.JWT.Secret,
+			JWKSFile:  cfg.JWT.JWKSFile,
+			Issuer:    cfg.JWT.Issuer,
+			Audience:  cfg.JWT.Audience,
+			UserClaim: cfg.JWT.UserClaim,
+			RoleClaim: cfg.JWT.RoleClaim,
+			AdminRole: cfg.JWT.AdminRole,
+			Leeway:    cfg.JWT.Leeway,
+		})
+		if err != nil {
+			return opts, err
+		}
+		opts.Tokens = verifier
+	}
+
+	return opts, nil
+}
+
+// rateLimits возвращает лимиты групп маршрутов из секции rate_limit конфига.
+func rateLimits(cfg *config.Config) (map[string]*ratelimit.Limiter, error) {
+	proxies, err := ratelimit.ParseProxies(cfg.RateLimit.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]*ratelimit.Limiter, len(cfg.RateLimit.Groups))
+	for group, g := range cfg.RateLimit.Groups {
+		switch group {
+		case groupSubscriptionsRead, groupSubscriptionsWrite, groupReports, groupAdmin:
+		case groupAuth:
+			if g.KeyBy != ratelimit.KeyByIP {
+				return nil, fmt.Errorf("rate limit group %q must use key_by %q", group, ratelimit.KeyByIP)
+			}
+		default:
+			return nil, fmt.Errorf("unknown rate limit group %q", group)
+		}
+
+		opts := ratelimit.Options{
+			Rate:        g.Rate,
+			Burst:       g.Burst,
+			KeyBy:       g.KeyBy,
+			MaxInFlight: g.MaxInFlight,
+			Proxies:     proxies,
+		}
+		if err := opts.Validate(); err != nil {
+			return nil, fmt.Errorf("rate limit group %q: %w", group, err)
+		}
+
+		limits[group] = ratelimit.NewLimiter(opts)
+	}
+
+	return limits, nil
+}
+
+// initRouter инициализирует роутер и подключает middleware. Если m не nil, запросы попадают в метрики HTTP.
+// При включенной трассировке на каждый запрос создается спан.
+func initRouter(cfg *config.Config, log *slog.Logger, m *metrics.Metrics) *chi.Mux {
 	slog.Info("Starting router")
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
+	if cfg.Tracing.Enabled {
+		router.Use(httptrace.New(log))
+	}
+	if m != nil {
+		router.Use(httpmetrics.New(log, m))
+	}
 	router.Use(logger.New(log))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		response.WriteError(w, r, response.NotFound, "no route for "+r.URL.Path)
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		response.WriteError(w, r, response.MethodNotAllowed, r.Method+" is not allowed for "+r.URL.Path)
+	})
 	slog.Debug("Middlewares used successfully",
 		slog.String("middleware", "middleware/RequestID"),
 		slog.String("middleware", "logger/New"),
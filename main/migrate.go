@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"gotest_23.07.25/internal/config"
+	"gotest_23.07.25/internal/migrator"
+	"gotest_23.07.25/internal/storage"
+)
+
+// migrate commands:
+const (
+	migrateUp      = "up"
+	migrateDown    = "down"
+	migrateVersion = "version"
+	migrateForce   = "force"
+	migrateCreate  = "create"
+)
+
+// defaultMigrationsDir - каталог исходных миграций для migrate create.
+const defaultMigrationsDir = "migrations"
+
+// errUsage означает неверные аргументы команды.
+var errUsage = errors.New("invalid arguments")
+
+// runMigrate выполняет команду migrate и возвращает код выхода процесса.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	cmd, args := args[0], args[1:]
+
+	if cmd == migrateCreate {
+		return exitCode(createMigration(args))
+	}
+
+	switch cmd {
+	case migrateUp, migrateDown, migrateVersion, migrateForce:
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n\n%s", cmd, usage)
+		return 2
+	}
+
+	cfg, log := loadConfig()
+	if cfg.Storage.Backend != storage.BackendSQL {
+		log.Error("migrations require the sql storage backend", slog.String("backend", cfg.Storage.Backend))
+		return 1
+	}
+
+	m, err := migrator.New(log, cfg.StorageLink.SQLDriver, config.GetStorageLink(cfg))
+	if err != nil {
+		log.Error("failed to init migrator", slog.String("error", err.Error()))
+		return 1
+	}
+	defer m.Close()
+
+	switch cmd {
+	case migrateUp:
+		err = migrateSteps(args, 0, m.Up)
+	case migrateDown:
+		err = migrateSteps(args, 1, m.Down)
+	case migrateForce:
+		err = forceVersion(args, m)
+	}
+	if err == nil {
+		err = printVersion(m)
+	}
+
+	return exitCode(err)
+}
+
+// migrateSteps вызывает fn с числом миграций из необязательного аргумента N или с def, если аргумента нет.
+func migrateSteps(args []string, def int, fn func(n int) error) error {
+	n := def
+	switch len(args) {
+	case 0:
+	case 1:
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 1 {
+			return fmt.Errorf("%w: N must be a positive number, got %q", errUsage, args[0])
+		}
+		n = v
+	default:
+		return fmt.Errorf("%w: expected at most one argument N", errUsage)
+	}
+
+	return fn(n)
+}
+
+// forceVersion записывает версию схемы из аргумента V.
+func forceVersion(args []string, m *migrator.Migrator) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: expected version V", errUsage)
+	}
+	v, err := strconv.Atoi(args[0])
+	if err != nil || v < -1 {
+		return fmt.Errorf("%w: V must be a migration version or -1, got %q", errUsage, args[0])
+	}
+
+	return m.Force(v)
+}
+
+// printVersion выводит версию примененной схемы.
+func printVersion(m *migrator.Migrator) error {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version == 0:
+		fmt.Println("no migrations applied")
+	case dirty:
+		fmt.Printf("version %d (dirty: fix the database and run migrate force)\n", version)
+	default:
+		fmt.Printf("version %d\n", version)
+	}
+
+	return nil
+}
+
+// createMigration создает файлы новой миграции. Конфиг и БД не нужны.
+func createMigration(args []string) error {
+	fs := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := fs.String("dir", defaultMigrationsDir, "migrations directory")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: expected migration NAME", errUsage)
+	}
+
+	files, err := migrator.Create(*dir, fs.Arg(0), time.Now())
+	for _, f := range files {
+		fmt.Println("created", f)
+	}
+
+	return err
+}
+
+// exitCode выводит ошибку команды и возвращает код выхода: 2 для неверных аргументов, 1 для остальных ошибок.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "%s\n\n%s", err, usage)
+		return 2
+	default:
+		slog.Error("migrate failed", slog.String("error", err.Error()))
+		return 1
+	}
+}
@@ -0,0 +1,12 @@
+ALTER TABLE subscriptions
+		DROP CONSTRAINT IF EXISTS subscriptions_period_excl,
+		DROP CONSTRAINT IF EXISTS subscriptions_period_check;
+
+-- Как и в SQLite, из нескольких периодов одной подписки остается самый поздний.
+DELETE FROM subscriptions s
+USING subscriptions newer
+WHERE newer.service_name = s.service_name
+		AND newer.user_id = s.user_id
+		AND (newer.start_date, newer.id) > (s.start_date, s.id);
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_service_name_user_id_key UNIQUE (service_name, user_id);
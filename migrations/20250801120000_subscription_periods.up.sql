@@ -0,0 +1,11 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_service_name_user_id_key;
+
+ALTER TABLE subscriptions
+		ADD CONSTRAINT subscriptions_period_check CHECK (end_date IS NULL OR end_date >= start_date),
+		ADD CONSTRAINT subscriptions_period_excl EXCLUDE USING gist (
+			service_name WITH =,
+			user_id WITH =,
+			daterange(start_date, end_date, '[]') WITH &&
+		);
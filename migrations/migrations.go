@@ -0,0 +1,56 @@
+// Package migrations встраивает SQL-миграции в бинарник сервиса.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// migration directories in FS:
+const (
+	DirPostgres = "."
+	DirSQLite   = "sqlite"
+)
+
+// FS - миграции postgres в корне и миграции SQLite в каталоге sqlite.
+//
+//go:embed *.sql sqlite/*.sql
+var FS embed.FS
+
+// Latest возвращает версию последней up-миграции в каталоге dir из FS.
+// Версия - числовой префикс имени файла до "_", как у golang-migrate.
+func Latest(dir string) (uint, error) {
+	const op = "migrations.Latest"
+
+	entries, err := fs.ReadDir(FS, dir)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var latest uint
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return 0, fmt.Errorf("%s: malformed migration name %q", op, name)
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: malformed migration version in %q: %w", op, name, err)
+		}
+		latest = max(latest, uint(version))
+	}
+
+	if latest == 0 {
+		return 0, fmt.Errorf("%s: no migrations in %q", op, dir)
+	}
+
+	return latest, nil
+}
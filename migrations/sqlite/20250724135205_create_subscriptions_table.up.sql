@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS subscriptions(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_name TEXT NOT NULL,
+		price INTEGER CHECK (price > 0),
+		user_id TEXT NOT NULL,
+		start_date TEXT NOT NULL,
+		end_date TEXT,
+		UNIQUE (service_name, user_id)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE subscriptions_old(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_name TEXT NOT NULL,
+		price INTEGER CHECK (price > 0),
+		user_id TEXT NOT NULL,
+		start_date TEXT NOT NULL,
+		end_date TEXT,
+		UNIQUE (service_name, user_id)
+);
+
+INSERT OR IGNORE INTO subscriptions_old (id, service_name, price, user_id, start_date, end_date)
+SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions ORDER BY start_date DESC;
+
+DROP TABLE subscriptions;
+
+ALTER TABLE subscriptions_old RENAME TO subscriptions;
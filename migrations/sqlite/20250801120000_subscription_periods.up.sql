@@ -0,0 +1,18 @@
+CREATE TABLE subscriptions_new(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_name TEXT NOT NULL,
+		price INTEGER CHECK (price > 0),
+		user_id TEXT NOT NULL,
+		start_date TEXT NOT NULL,
+		end_date TEXT,
+		CHECK (end_date IS NULL OR end_date >= start_date)
+);
+
+INSERT INTO subscriptions_new (id, service_name, price, user_id, start_date, end_date)
+SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions;
+
+DROP TABLE subscriptions;
+
+ALTER TABLE subscriptions_new RENAME TO subscriptions;
+
+CREATE INDEX subscriptions_period_idx ON subscriptions (service_name, user_id, start_date);